@@ -1,10 +1,113 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"github.com/Sarraksh/OTRS-time-accounting/internal/service"
+	"log"
+	"os"
 )
 
 func main() {
+	// One-shot commands.
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "copy-sqlite":
+			copySQLite(os.Args[2:])
+			return
+		case "import-calendar":
+			importCalendar(os.Args[2:])
+			return
+		case "resync":
+			resync(os.Args[2:])
+			return
+		case "reconcile":
+			reconcile(os.Args[2:])
+			return
+		default:
+			log.Fatalf("unknown command '%s'", os.Args[1])
+		}
+	}
+
 	// Start service instance.
 	service.Start()
 }
+
+// Copy data from sqlite file into configured PostgreSQL internal DB.
+func copySQLite(args []string) {
+	fs := flag.NewFlagSet("copy-sqlite", flag.ExitOnError)
+	from := fs.String("from", "sqlite.db", "sqlite DB file to copy from")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: app copy-sqlite [-from sqlite.db]")
+		fmt.Fprintln(fs.Output(), "Copy all data from sqlite file into empty PostgreSQL internal DB configured in config.yaml.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	err := service.CopySQLite(*from)
+	if err != nil {
+		log.Fatalf("Copy failed '%v'", err)
+	}
+}
+
+// Import production calendar into internal DB.
+func importCalendar(args []string) {
+	fs := flag.NewFlagSet("import-calendar", flag.ExitOnError)
+	file := fs.String("file", "", "production calendar file (.xml in xmlcalendar format or .csv)")
+	apply := fs.Bool("apply", false, "write changes into internal DB, otherwise only show difference")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: app import-calendar -file calendar.xml [-apply]")
+		fmt.Fprintln(fs.Output(), "Show difference between production calendar and overridden days in internal DB and apply it.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if *file == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	err := service.ImportCalendar(*file, *apply)
+	if err != nil {
+		log.Fatalf("Import failed '%v'", err)
+	}
+}
+
+// Re-sync accounted time of period from OTRS into internal DB.
+func resync(args []string) {
+	fs := flag.NewFlagSet("resync", flag.ExitOnError)
+	from := fs.String("from", "", "first day of period in 2006.01.02 format")
+	to := fs.String("to", "", "last day of period in 2006.01.02 format")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: app resync -from 2021.01.01 -to 2021.12.31")
+		fmt.Fprintln(fs.Output(), "Re-sync accounted time of period from OTRS into internal DB configured in config.yaml.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	if *from == "" || *to == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	err := service.Resync(*from, *to)
+	if err != nil {
+		log.Fatalf("Re-sync failed '%v'", err)
+	}
+}
+
+// Compare stored accounted time with OTRS and correct mismatches.
+func reconcile(args []string) {
+	fs := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	weeks := fs.Int("weeks", 4, "number of last weeks to compare")
+	apply := fs.Bool("apply", false, "correct mismatches in internal DB, otherwise only show them")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: app reconcile [-weeks 4] [-apply]")
+		fmt.Fprintln(fs.Output(), "Show mismatches between accounted time in internal DB and OTRS for last weeks and correct them.")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	err := service.Reconcile(*weeks, *apply)
+	if err != nil {
+		log.Fatalf("Reconciliation failed '%v'", err)
+	}
+}
@@ -0,0 +1,149 @@
+package calendar
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// One day of production calendar, which differs from default weekday/weekend rule.
+type Day struct {
+	Date   time.Time // Day in UTC.
+	Type   string    // One of internalDB DayType constants.
+	Reason string
+}
+
+// Read production calendar from file.
+// Format is defined by extension: ".xml" for xmlcalendar format, ".csv" for CSV.
+// Returned days are sorted by date.
+func ReadFile(fileName string) ([]Day, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var dayList []Day
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".xml":
+		dayList, err = ParseXML(file)
+	case ".csv":
+		dayList, err = ParseCSV(file)
+	default:
+		return nil, fmt.Errorf("unknown calendar file format '%s', expected .xml or .csv", fileName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(dayList, func(i, j int) bool {
+		return dayList[i].Date.Before(dayList[j].Date)
+	})
+	return dayList, nil
+}
+
+// Production calendar in xmlcalendar format, e.g.
+//
+//	<calendar year="2021" lang="ru">
+//	  <holidays><holiday id="1" title="Новогодние каникулы"/></holidays>
+//	  <days><day d="01.01" t="1" h="1"/><day d="02.20" t="3" f="11.04"/></days>
+//	</calendar>
+//
+// Day type "t": 1 - day off, 2 - shortened day, 3 - working day. "h" - holiday id, "f" - day from which working day is transferred.
+type xmlCalendar struct {
+	Year     int `xml:"year,attr"`
+	Holidays []struct {
+		ID    string `xml:"id,attr"`
+		Title string `xml:"title,attr"`
+	} `xml:"holidays>holiday"`
+	Days []struct {
+		D string `xml:"d,attr"` // Day in "01.02" (month.day) format.
+		T int    `xml:"t,attr"`
+		H string `xml:"h,attr"`
+		F string `xml:"f,attr"`
+	} `xml:"days>day"`
+}
+
+// Parse production calendar in xmlcalendar format.
+func ParseXML(r io.Reader) ([]Day, error) {
+	var cal xmlCalendar
+	err := xml.NewDecoder(r).Decode(&cal)
+	if err != nil {
+		return nil, err
+	}
+	if cal.Year == 0 {
+		return nil, fmt.Errorf("calendar year is not specified")
+	}
+
+	holidayTitle := make(map[string]string, len(cal.Holidays))
+	for _, h := range cal.Holidays {
+		holidayTitle[h.ID] = h.Title
+	}
+
+	dayList := make([]Day, 0, len(cal.Days))
+	for _, d := range cal.Days {
+		date, err := time.Parse("2006.01.02", fmt.Sprintf("%d.%s", cal.Year, d.D))
+		if err != nil {
+			return nil, fmt.Errorf("invalid day '%s' '%w'", d.D, err)
+		}
+
+		day := Day{Date: date, Reason: holidayTitle[d.H]}
+		switch d.T {
+		case 1:
+			day.Type = internalDB.DayTypeHoliday
+		case 2:
+			day.Type = internalDB.DayTypeShortened
+		case 3:
+			day.Type = internalDB.DayTypeTransferredWorkday
+			if d.F != "" && day.Reason == "" {
+				day.Reason = fmt.Sprintf("перенос с %s", d.F)
+			}
+		default:
+			return nil, fmt.Errorf("unknown type '%v' of day '%s'", d.T, d.D)
+		}
+		dayList = append(dayList, day)
+	}
+	return dayList, nil
+}
+
+// Parse production calendar in CSV format.
+// Every line is "date,type,reason": date in "2006.01.02" format, type is one of internalDB DayType constants, reason is optional.
+// Lines starting with "#" are skipped.
+func ParseCSV(r io.Reader) ([]Day, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	recordList, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	dayList := make([]Day, 0, len(recordList))
+	for i, record := range recordList {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %v: expected 'date,type,reason'", i+1)
+		}
+		date, err := time.Parse("2006.01.02", record[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %v: invalid date '%s'", i+1, record[0])
+		}
+		if !internalDB.IsValidDayType(record[1]) {
+			return nil, fmt.Errorf("line %v: unknown day type '%s'", i+1, record[1])
+		}
+		day := Day{Date: date, Type: record[1]}
+		if len(record) > 2 {
+			day.Reason = record[2]
+		}
+		dayList = append(dayList, day)
+	}
+	return dayList, nil
+}
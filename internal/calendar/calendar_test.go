@@ -0,0 +1,107 @@
+package calendar
+
+import (
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"strings"
+	"testing"
+)
+
+// Format day list for comparison.
+func formatDayList(dayList []Day) string {
+	lineList := make([]string, 0, len(dayList))
+	for _, day := range dayList {
+		lineList = append(lineList, fmt.Sprintf("%s %s %s", day.Date.Format("2006.01.02"), day.Type, day.Reason))
+	}
+	return strings.Join(lineList, "; ")
+}
+
+func TestParseXML(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		xml      string
+		expected string
+		isError  bool
+	}{
+		{
+			name: "day types",
+			xml: `<calendar year="2021" lang="ru">
+				<holidays><holiday id="1" title="Новогодние каникулы"/></holidays>
+				<days>
+					<day d="01.04" t="1" h="1"/>
+					<day d="02.20" t="3" f="11.04"/>
+					<day d="04.30" t="2"/>
+				</days>
+			</calendar>`,
+			expected: "2021.01.04 " + internalDB.DayTypeHoliday + " Новогодние каникулы; " +
+				"2021.02.20 " + internalDB.DayTypeTransferredWorkday + " перенос с 11.04; " +
+				"2021.04.30 " + internalDB.DayTypeShortened + " ",
+		},
+		{
+			name:     "month before day",
+			xml:      `<calendar year="2021"><days><day d="11.04" t="1"/></days></calendar>`,
+			expected: "2021.11.04 " + internalDB.DayTypeHoliday + " ",
+		},
+		{
+			name:     "holiday title is kept for transferred workday",
+			xml:      `<calendar year="2021"><holidays><holiday id="2" title="Перенос"/></holidays><days><day d="02.20" t="3" h="2" f="11.04"/></days></calendar>`,
+			expected: "2021.02.20 " + internalDB.DayTypeTransferredWorkday + " Перенос",
+		},
+		{name: "no year", xml: `<calendar><days><day d="01.01" t="1"/></days></calendar>`, isError: true},
+		{name: "unknown type", xml: `<calendar year="2021"><days><day d="01.01" t="4"/></days></calendar>`, isError: true},
+		{name: "invalid day", xml: `<calendar year="2021"><days><day d="13.01" t="1"/></days></calendar>`, isError: true},
+		{name: "invalid xml", xml: `<calendar year="2021">`, isError: true},
+	} {
+		dayList, err := ParseXML(strings.NewReader(c.xml))
+		if c.isError {
+			if err == nil {
+				t.Errorf("%s: expected error, got %s", c.name, formatDayList(dayList))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error '%v'", c.name, err)
+			continue
+		}
+		if formatDayList(dayList) != c.expected {
+			t.Errorf("%s: got '%s', expected '%s'", c.name, formatDayList(dayList), c.expected)
+		}
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		csv      string
+		expected string
+		isError  bool
+	}{
+		{
+			name: "days with and without reason",
+			csv: "# date,type,reason\n" +
+				"2021.01.04," + internalDB.DayTypeHoliday + ",New year\n" +
+				"2021.02.20, " + internalDB.DayTypeTransferredWorkday + "\n",
+			expected: "2021.01.04 " + internalDB.DayTypeHoliday + " New year; " +
+				"2021.02.20 " + internalDB.DayTypeTransferredWorkday + " ",
+		},
+		{name: "empty", csv: "", expected: ""},
+		{name: "no type", csv: "2021.01.04\n", isError: true},
+		{name: "invalid date", csv: "04.01.2021," + internalDB.DayTypeHoliday + "\n", isError: true},
+		{name: "unknown type", csv: "2021.01.04,vacation\n", isError: true},
+	} {
+		dayList, err := ParseCSV(strings.NewReader(c.csv))
+		if c.isError {
+			if err == nil {
+				t.Errorf("%s: expected error, got %s", c.name, formatDayList(dayList))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error '%v'", c.name, err)
+			continue
+		}
+		if formatDayList(dayList) != c.expected {
+			t.Errorf("%s: got '%s', expected '%s'", c.name, formatDayList(dayList), c.expected)
+		}
+	}
+}
@@ -8,14 +8,41 @@ import (
 )
 
 // Store all configuration options.
+// Mode "demo" starts service with generated or fixture data instead of OTRS.
 type Config struct {
+	Mode           string         `yaml:"Mode"`
+	Demo           Demo           `yaml:"Demo"`
 	OTRSConnection OTRSConnection `yaml:"OTRSConnection"`
+	InternalDB     InternalDB     `yaml:"InternalDB"`
 	Web            Web            `yaml:"Web"`
 	UserList       []User         `yaml:"UserList"`
+	TicketStates   TicketStates   `yaml:"TicketStates"`
+	Schedule       Schedule       `yaml:"Schedule"`
 }
 
 // Connection to OTRS DB.
+// Driver selects the OTRS DB backend: "postgres" (default), "mysql" (MySQL and MariaDB)
+// or "rest" (GenericInterface web service, uses URL and WebService
+// and agent credentials from UserName and Password).
 type OTRSConnection struct {
+	Driver        string `yaml:"Driver"`
+	Host          string `yaml:"Host"`
+	Port          string `yaml:"Port"`
+	UserName      string `yaml:"UserName"`
+	Password      string `yaml:"Password"`
+	DBName        string `yaml:"DBName"`
+	SSLMode       string `yaml:"SSLMode"`
+	URL           string `yaml:"URL"`           // OTRS base URL, e.g. https://otrs.local/otrs .
+	WebService    string `yaml:"WebService"`    // GenericInterface web service name.
+	OvertimeField string `yaml:"OvertimeField"` // Name of article dynamic field which marks overtime.
+}
+
+// Storage of collected statistic.
+// Driver selects the storage: "sqlite" (default, FileName or "sqlite.db")
+// or "postgres" (shared by several service instances, uses Host, Port, UserName, Password, DBName and SSLMode).
+type InternalDB struct {
+	Driver   string `yaml:"Driver"`
+	FileName string `yaml:"FileName"`
 	Host     string `yaml:"Host"`
 	Port     string `yaml:"Port"`
 	UserName string `yaml:"UserName"`
@@ -24,16 +51,70 @@ type OTRSConnection struct {
 	SSLMode  string `yaml:"SSLMode"`
 }
 
+// Demo mode data source.
+// Data is read from FixtureFile (YAML or JSON) or generated with Seed if file is not specified.
+type Demo struct {
+	FixtureFile string `yaml:"FixtureFile"`
+	Seed        int64  `yaml:"Seed"`
+}
+
 // Web interface.
+// APIToken is required by API which changes a lot of data (re-sync), such API is disabled if token is empty.
 type Web struct {
-	Port string `yaml:"Port"`
+	Port     string `yaml:"Port"`
+	APIToken string `yaml:"APIToken"`
+}
+
+// Cron-like schedules ("minute hour day-of-month month day-of-week") of regular synchronisation with OTRS.
+// Empty schedule means default: today refresh every 15 minutes, yesterday finalization at 00:10
+// and re-sync of last ResyncDays days (14 by default) on Monday at 01:30.
+// Reconciliation compares stored time of last ReconcileWeeks weeks (4 by default) with OTRS on Sunday at 03:00 by default
+// and corrects mismatches if ReconcileApply is true.
+// Failed job is retried up to RetryAttempts times (5 by default) with delay doubled
+// from RetryDelay (30 seconds by default) up to RetryMaxDelay (300 seconds by default).
+type Schedule struct {
+	TodayRefresh          string `yaml:"TodayRefresh"`
+	YesterdayFinalization string `yaml:"YesterdayFinalization"`
+	WeeklyResync          string `yaml:"WeeklyResync"`
+	ResyncDays            int    `yaml:"ResyncDays"`
+	Reconciliation        string `yaml:"Reconciliation"`
+	ReconcileWeeks        int    `yaml:"ReconcileWeeks"`
+	ReconcileApply        bool   `yaml:"ReconcileApply"`
+	RetryAttempts         int    `yaml:"RetryAttempts"`
+	RetryDelay            int    `yaml:"RetryDelay"`    // Seconds.
+	RetryMaxDelay         int    `yaml:"RetryMaxDelay"` // Seconds.
+}
+
+// Ticket state types (names from OTRS ticket_state_type table) counted as closed and open on the Today page.
+// Default is "closed" and "merged" for ClosedTypes and "open" for OpenTypes.
+type TicketStates struct {
+	ClosedTypes []string `yaml:"ClosedTypes"`
+	OpenTypes   []string `yaml:"OpenTypes"`
 }
 
 // Users for whom information is displayed in the web interface.
+// Users are identified by OTRS Login, LastName is used for display only.
+// UserID is OTRS user ID, required by "rest" driver only.
 type User struct {
+	Login     string `yaml:"Login"`
 	LastName  string `yaml:"LastName"`
 	WorkShift string `yaml:"WorkShift"`
 	Command   int    `yaml:"Command"`
+	UserID    int    `yaml:"UserID"`
+}
+
+// Placeholder of secret values in redacted config.
+const redactedValue = "***"
+
+// Return copy of config with passwords and API token replaced by placeholder, e.g. for logging.
+// Empty secrets stay empty, so missing secret is visible.
+func (c Config) Redacted() Config {
+	for _, secret := range []*string{&c.OTRSConnection.Password, &c.InternalDB.Password, &c.Web.APIToken} {
+		if *secret != "" {
+			*secret = redactedValue
+		}
+	}
+	return c
 }
 
 // Extract configuration file and unmarshall collected data into config variable.
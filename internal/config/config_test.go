@@ -0,0 +1,21 @@
+package config
+
+import "testing"
+
+func TestRedacted(t *testing.T) {
+	var cfg Config
+	cfg.OTRSConnection.UserName = "otrs"
+	cfg.OTRSConnection.Password = "otrs secret"
+	cfg.Web.APIToken = "token"
+
+	redacted := cfg.Redacted()
+	if redacted.OTRSConnection.Password != redactedValue || redacted.Web.APIToken != redactedValue {
+		t.Errorf("secrets aren't redacted %+v", redacted)
+	}
+	if redacted.InternalDB.Password != "" || redacted.OTRSConnection.UserName != "otrs" {
+		t.Errorf("unexpected redacted config %+v", redacted)
+	}
+	if cfg.OTRSConnection.Password != "otrs secret" || cfg.Web.APIToken != "token" {
+		t.Errorf("original config is changed %+v", cfg)
+	}
+}
@@ -1,12 +1,16 @@
 package goviewEcho
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
 	"fmt"
 	"github.com/Sarraksh/OTRS-time-accounting/internal/httpServer"
 	"github.com/foolin/goview"
 	"github.com/foolin/goview/supports/echoview-v4"
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
+	"io"
 	"net/http"
 	"time"
 )
@@ -19,7 +23,7 @@ type Provider struct {
 }
 
 // Initialise and return web service provider.
-func NewProvider(todayData *httpServer.TodayStatistic, getCurrentWeekData, getLastWeekData func() (httpServer.WeekStatistic, error), setWDO, removeWDO chan string) httpServer.Provider {
+func NewProvider(conn httpServer.Connectors) httpServer.Provider {
 	// Initial echo instance.
 	e := echo.New()
 
@@ -30,17 +34,31 @@ func NewProvider(todayData *httpServer.TodayStatistic, getCurrentWeekData, getLa
 	// Set default renderer with custom template location.
 	gvConf := goview.DefaultConfig
 	gvConf.Root = "website" // Set template folder.
-	e.Renderer = echoview.New(gvConf)
+	e.Renderer = syncStateRenderer{Renderer: echoview.New(gvConf), getSyncState: conn.GetSyncState}
 
 	// Set router schema.
-	e = setPageRouter(e, todayData, getCurrentWeekData, getLastWeekData)
-	e = setAPIRouter(e, setWDO, removeWDO)
+	e = setPageRouter(e, conn)
+	e = setAPIRouter(e, conn)
 
-	return Provider{Echo: e, TodayData: todayData}
+	return Provider{Echo: e, TodayData: conn.TodayData}
+}
+
+// Renderer which adds synchronisation state to data of every page, so stale data is marked by page layout.
+type syncStateRenderer struct {
+	echo.Renderer
+	getSyncState func() httpServer.SyncState
+}
+
+// Render template with "syncState" added to page data.
+func (r syncStateRenderer) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
+	if pageData, ok := data.(echo.Map); ok && r.getSyncState != nil {
+		pageData["syncState"] = r.getSyncState()
+	}
+	return r.Renderer.Render(w, name, data, c)
 }
 
 // Initialise pages for web interface.
-func setPageRouter(e *echo.Echo, todayData *httpServer.TodayStatistic, getCurrentWeekData, getLastWeekData func() (httpServer.WeekStatistic, error)) *echo.Echo {
+func setPageRouter(e *echo.Echo, conn httpServer.Connectors) *echo.Echo {
 	// Favicon.
 	e.GET("/favicon.ico", wrapperFavIco())
 
@@ -49,7 +67,7 @@ func setPageRouter(e *echo.Echo, todayData *httpServer.TodayStatistic, getCurren
 		date := time.Now().Format("2006.02.01")
 		timeNow := time.Now().Format("15:04:05")
 
-		prodData, updateDateTime := todayData.Get()
+		prodData, updateDateTime := conn.TodayData.Get()
 		updateDateTimeText := updateDateTime.Format("2006.02.01 15:04:05")
 
 		// Render with page master.html.
@@ -63,10 +81,25 @@ func setPageRouter(e *echo.Echo, todayData *httpServer.TodayStatistic, getCurren
 	})
 
 	// Current week statistic page.
-	e.GET("/currentweek", wrapperWeek(getCurrentWeekData, "Current week", "Списано за текущую неделю"))
+	e.GET("/currentweek", wrapperWeek(conn.GetCurrentWeekData, "Current week", "Списано за текущую неделю"))
 
 	// Last week statistic page.
-	e.GET("/lastweek", wrapperWeek(getLastWeekData, "Last week", "Списано за прошлую неделю"))
+	e.GET("/lastweek", wrapperWeek(conn.GetLastWeekData, "Last week", "Списано за прошлую неделю"))
+
+	// Accounted time entries of one user for one day.
+	e.GET("/details", wrapperDetails(conn.GetDayDetails))
+
+	// Accounted time by queue, service and customer for a period.
+	e.GET("/report", wrapperReport(conn.GetReport))
+
+	// Change history of stored accounted time of one user for one day.
+	e.GET("/history", wrapperHistory(conn.GetHistory))
+
+	// User absences management.
+	e.GET("/absences", wrapperAbsences(conn.GetAbsences))
+
+	// Report of the last reconciliation of stored accounted time with OTRS.
+	e.GET("/reconciliation", wrapperReconciliation(conn.GetReconciliation))
 
 	return e
 }
@@ -77,7 +110,7 @@ func wrapperWeek(getData func() (httpServer.WeekStatistic, error), title, pageNa
 		dataTable, err := getData()
 		if err != nil {
 			// TODO - use error page template
-			return c.String(http.StatusInternalServerError, fmt.Sprintf("Internal server error. Can't read statistic from internal storage.\n'%v'", err))
+			return c.String(errorStatus(err), fmt.Sprintf("Can't read statistic from internal storage.\n'%v'", err))
 		}
 		pageOpenTime := time.Now().Format("2006.02.01 15:04:05")
 
@@ -91,23 +124,283 @@ func wrapperWeek(getData func() (httpServer.WeekStatistic, error), title, pageNa
 	}
 }
 
+// Return handler function for accounted time details render.
+// Expects "user" (OTRS login) and "day" (in "2006.01.02" format) query parameters.
+func wrapperDetails(getData func(login, date string) (httpServer.DayDetails, error)) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		details, err := getData(c.QueryParam("user"), c.QueryParam("day"))
+		if err != nil {
+			// TODO - use error page template
+			return c.String(errorStatus(err), fmt.Sprintf("Can't read accounted time details from OTRS.\n'%v'", err))
+		}
+		pageOpenTime := time.Now().Format("2006.02.01 15:04:05")
+
+		//render with master
+		return c.Render(http.StatusOK, "details", echo.Map{
+			"title":        "Details",
+			"pageOpenTime": pageOpenTime,
+			"details":      details,
+		})
+	}
+}
+
+// Return handler function for accounted time report render.
+// Expects optional "from" and "to" (in "2006.01.02" format) query parameters.
+func wrapperReport(getData func(from, to string) (httpServer.Report, error)) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		report, err := getData(c.QueryParam("from"), c.QueryParam("to"))
+		if err != nil {
+			// TODO - use error page template
+			return c.String(errorStatus(err), fmt.Sprintf("Can't build report.\n'%v'", err))
+		}
+		pageOpenTime := time.Now().Format("2006.02.01 15:04:05")
+
+		//render with master
+		return c.Render(http.StatusOK, "report", echo.Map{
+			"title":        "Report",
+			"pageOpenTime": pageOpenTime,
+			"report":       report,
+		})
+	}
+}
+
+// Return handler function for accounted time change history render.
+// Expects "user" (OTRS login) and "day" (in "2006.01.02" format) query parameters.
+// Returns JSON instead of page if "format=json" query parameter is present.
+func wrapperHistory(getData func(login, date string) (httpServer.History, error)) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		history, err := getData(c.QueryParam("user"), c.QueryParam("day"))
+		if err != nil {
+			// TODO - use error page template
+			return c.String(errorStatus(err), fmt.Sprintf("Can't read accounted time history from internal storage.\n'%v'", err))
+		}
+		if c.QueryParam("format") == "json" {
+			return c.JSON(http.StatusOK, history)
+		}
+		pageOpenTime := time.Now().Format("2006.02.01 15:04:05")
+
+		//render with master
+		return c.Render(http.StatusOK, "history", echo.Map{
+			"title":        "History",
+			"pageOpenTime": pageOpenTime,
+			"history":      history,
+		})
+	}
+}
+
+// Return handler function for absences page render.
+// Expects optional "from" and "to" (in "2006.01.02" format) query parameters.
+func wrapperAbsences(getData func(from, to string) (httpServer.Absences, error)) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		absences, err := getData(c.QueryParam("from"), c.QueryParam("to"))
+		if err != nil {
+			// TODO - use error page template
+			return c.String(errorStatus(err), fmt.Sprintf("Can't read absences.\n'%v'", err))
+		}
+		pageOpenTime := time.Now().Format("2006.02.01 15:04:05")
+
+		//render with master
+		return c.Render(http.StatusOK, "absences", echo.Map{
+			"title":        "Absences",
+			"pageOpenTime": pageOpenTime,
+			"absences":     absences,
+		})
+	}
+}
+
+// Return handler function for reconciliation report page.
+// Responds with JSON if "format" query parameter is "json".
+func wrapperReconciliation(getData func() httpServer.Reconciliation) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		report := getData()
+		if c.QueryParam("format") == "json" {
+			return c.JSON(http.StatusOK, report)
+		}
+		pageOpenTime := time.Now().Format("2006.02.01 15:04:05")
+
+		//render with master
+		return c.Render(http.StatusOK, "reconciliation", echo.Map{
+			"title":        "Reconciliation",
+			"pageOpenTime": pageOpenTime,
+			"report":       report,
+		})
+	}
+}
+
 // Initialise web API.
-func setAPIRouter(e *echo.Echo, setWDO, removeWDO chan string) *echo.Echo {
+func setAPIRouter(e *echo.Echo, conn httpServer.Connectors) *echo.Echo {
 	// API.
-	e.POST("/workingDayOverride", wrapperOverrideDay(setWDO))
-	e.DELETE("/workingDayOverride", wrapperOverrideDay(removeWDO))
+	e.GET("/workingDayOverride", wrapperOverrideDayList(conn.GetWDOList))
+	e.POST("/workingDayOverride", wrapperSetOverrideDay(conn.SetWDO))
+	e.DELETE("/workingDayOverride", wrapperRemoveOverrideDay(conn.RemoveWDO))
+	e.GET("/absence", wrapperAbsenceList(conn.GetAbsences))
+	e.POST("/absence", wrapperAddAbsence(conn.AddAbsence))
+	e.DELETE("/absence", wrapperRemoveAbsence(conn.RemoveAbsence))
+	e.GET("/jobs", wrapperJobs(conn.GetJobs))
+
+	// Protected API.
+	e.GET("/resync", wrapperResyncProgress(conn.GetResyncProgress), requireToken(conn.APIToken))
+	e.POST("/resync", wrapperStartResync(conn.StartResync), requireToken(conn.APIToken))
 
 	return e
 }
 
-// Return handler function for add or remove day override.
-func wrapperOverrideDay(output chan string) func(c echo.Context) error {
+// Return handler function for overridden day list.
+// Expects "from" and "to" (in "2006.01.02" format) query parameters.
+func wrapperOverrideDayList(getData func(from, to string) ([]httpServer.DayOverride, error)) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		woList, err := getData(c.QueryParam("from"), c.QueryParam("to"))
+		if err != nil {
+			return c.String(errorStatus(err), fmt.Sprintf("Can't read workday overrides.\n'%v'", err))
+		}
+		return c.JSON(http.StatusOK, woList)
+	}
+}
+
+// Return handler function for add or replace day override.
+// Expects "day" and optional "type", "norm", "reason" and "author" form values.
+// Client IP is used as author if author is not specified.
+func wrapperSetOverrideDay(apply func(wo httpServer.DayOverride) error) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		wo := httpServer.DayOverride{
+			Date:   c.FormValue("day"),
+			Type:   c.FormValue("type"),
+			Norm:   c.FormValue("norm"),
+			Reason: c.FormValue("reason"),
+			Author: c.FormValue("author"),
+		}
+		if wo.Author == "" {
+			wo.Author = c.RealIP()
+		}
+		err := apply(wo)
+		if err != nil {
+			return c.String(errorStatus(err), fmt.Sprintf("Can't change workday override.\n'%v'", err))
+		}
+		return c.NoContent(http.StatusOK)
+	}
+}
+
+// Return handler function for remove day override.
+func wrapperRemoveOverrideDay(apply func(date string) error) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		output <- c.FormValue("day")
+		err := apply(c.FormValue("day"))
+		if err != nil {
+			return c.String(errorStatus(err), fmt.Sprintf("Can't change workday override.\n'%v'", err))
+		}
 		return c.NoContent(http.StatusOK)
 	}
 }
 
+// Return handler function for absence list.
+// Expects optional "from" and "to" (in "2006.01.02" format) query parameters.
+func wrapperAbsenceList(getData func(from, to string) (httpServer.Absences, error)) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		absences, err := getData(c.QueryParam("from"), c.QueryParam("to"))
+		if err != nil {
+			return c.String(errorStatus(err), fmt.Sprintf("Can't read absences.\n'%v'", err))
+		}
+		return c.JSON(http.StatusOK, absences.Data)
+	}
+}
+
+// Return handler function for add absence.
+// Expects "user", "from", "to", "type" and optional "comment" and "author" form values.
+// Client IP is used as author if author is not specified. Responds with ID of added absence.
+func wrapperAddAbsence(add func(a httpServer.AbsenceRequest) (int64, error)) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		a := httpServer.AbsenceRequest{
+			Login:   c.FormValue("user"),
+			From:    c.FormValue("from"),
+			To:      c.FormValue("to"),
+			Type:    c.FormValue("type"),
+			Comment: c.FormValue("comment"),
+			Author:  c.FormValue("author"),
+		}
+		if a.Author == "" {
+			a.Author = c.RealIP()
+		}
+		id, err := add(a)
+		if err != nil {
+			return c.String(errorStatus(err), fmt.Sprintf("Can't add absence.\n'%v'", err))
+		}
+		return c.JSON(http.StatusOK, echo.Map{"id": id})
+	}
+}
+
+// Return handler function for remove absence.
+// Expects "id" form value.
+func wrapperRemoveAbsence(remove func(id string) error) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		err := remove(c.FormValue("id"))
+		if err != nil {
+			return c.String(errorStatus(err), fmt.Sprintf("Can't remove absence.\n'%v'", err))
+		}
+		return c.NoContent(http.StatusOK)
+	}
+}
+
+// Return handler function for state of regular jobs.
+func wrapperJobs(getData func() []httpServer.JobStatus) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, getData())
+	}
+}
+
+// Return middleware which allows request only with "Authorization: Bearer <token>" header.
+// All requests are forbidden if token is empty.
+func requireToken(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if token == "" {
+				return c.String(http.StatusForbidden, "API is disabled, set APIToken in config to enable it.")
+			}
+			provided := c.Request().Header.Get(echo.HeaderAuthorization)
+			if subtle.ConstantTimeCompare([]byte(provided), []byte("Bearer "+token)) != 1 {
+				return c.String(http.StatusUnauthorized, "Invalid or missing API token.")
+			}
+			return next(c)
+		}
+	}
+}
+
+// Return handler function for start re-sync.
+// Expects "from" and "to" (in "2006.01.02" format) and optional "author" form values.
+// Client IP is used as author if author is not specified. Responds with re-sync progress.
+func wrapperStartResync(start func(from, to, author string) (httpServer.ResyncProgress, error)) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		author := c.FormValue("author")
+		if author == "" {
+			author = c.RealIP()
+		}
+		progress, err := start(c.FormValue("from"), c.FormValue("to"), author)
+		if err != nil {
+			return c.String(errorStatus(err), fmt.Sprintf("Can't start re-sync.\n'%v'", err))
+		}
+		return c.JSON(http.StatusAccepted, progress)
+	}
+}
+
+// Return handler function for re-sync progress.
+func wrapperResyncProgress(getData func() httpServer.ResyncProgress) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, getData())
+	}
+}
+
+// Return HTTP status code for connector error.
+func errorStatus(err error) int {
+	if errors.Is(err, httpServer.ErrBadRequest) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, httpServer.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, httpServer.ErrConflict) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
 // Return handler function for favicon.
 func wrapperFavIco() func(c echo.Context) error {
 	return func(c echo.Context) error {
@@ -116,7 +409,12 @@ func wrapperFavIco() func(c echo.Context) error {
 }
 
 // Start HTTP server.
-func (p Provider) ListenAndServe(port string) {
+func (p Provider) ListenAndServe(port string) error {
 	port = fmt.Sprintf(":%s", port)
-	p.Echo.Logger.Fatal(p.Echo.Start(port))
+	return p.Echo.Start(port)
+}
+
+// Gracefully stop HTTP server.
+func (p Provider) Shutdown(ctx context.Context) error {
+	return p.Echo.Shutdown(ctx)
 }
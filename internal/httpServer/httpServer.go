@@ -1,13 +1,28 @@
 package httpServer
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"time"
 )
 
+// Connectors wrap these errors for invalid user input (dates, ranges, etc.), missing objects
+// and operations which are already in progress, so HTTP server can respond with
+// "400 Bad Request", "404 Not Found" and "409 Conflict".
+// Other connector errors are responded with "500 Internal Server Error".
+var (
+	ErrBadRequest = errors.New("bad request")
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+)
+
 // Interface for HTTP server.
 type Provider interface {
-	ListenAndServe(port string)
+	// Serve HTTP requests until Shutdown. Return http.ErrServerClosed after Shutdown.
+	ListenAndServe(port string) error
+	// Stop accepting new requests and wait for requests in progress until ctx is done.
+	Shutdown(ctx context.Context) error
 }
 
 // Help receive today data from main service.
@@ -31,6 +46,7 @@ type TodayStatisticRow struct {
 // Help receive week data from main service.
 type WeekStatistic struct {
 	HeaderColor []string
+	HeaderTitle []string // Type and reason of overridden days.
 	Data        []WeekStatisticRow
 }
 
@@ -40,6 +56,7 @@ type WeekStatisticRow struct {
 }
 
 type UserCell struct {
+	Login          string
 	LastName       string
 	WorkShiftColor string
 	LastInGroup    bool
@@ -50,6 +67,127 @@ type TimeAccounted struct {
 	Overtime         int64
 	IsOverTimeExists bool
 	Color            string
+	Date             string // Day in "2006.01.02" format. Empty for week total.
+	Absence          string // Absence type name if user is absent this day.
+}
+
+// Help receive accounted time entries of one user for one day from main service.
+type DayDetails struct {
+	Login    string
+	LastName string
+	Date     string
+	WorkTime int
+	OverTime int
+	Data     []DayDetailsRow
+}
+
+type DayDetailsRow struct {
+	TicketNumber string
+	Title        string
+	Queue        string
+	ArticleID    int
+	Minutes      int
+	IsOverTime   bool
+}
+
+// Help receive change history of stored accounted time of one user for one day from main service.
+type History struct {
+	LastName string
+	Date     string // Day in "2006.01.02" format.
+	Data     []HistoryRow
+}
+
+type HistoryRow struct {
+	ChangedAt   string // Synchronisation time in "2006.01.02 15:04:05" format.
+	Source      string
+	IsNew       bool // True if time was stored for the first time.
+	OldWorkTime int64
+	OldOverTime int64
+	NewWorkTime int64
+	NewOverTime int64
+}
+
+// Overridden day received from and sent to web API.
+// Type is one of "holiday", "transferredWorkday", "shortened", "corporateDayOff".
+// Norm is work time norm in minutes, empty for default norm of day type.
+type DayOverride struct {
+	Date      string `json:"date"` // Day in "2006.01.02" format.
+	Type      string `json:"type"`
+	Norm      string `json:"norm,omitempty"`
+	Reason    string `json:"reason"`
+	Author    string `json:"author"`
+	CreatedAt string `json:"createdAt,omitempty"` // Time in "2006.01.02 15:04:05" format, filled by main service.
+}
+
+// Help receive user absences for a period from main service.
+type Absences struct {
+	From  string     // First day of period in "2006.01.02" format.
+	To    string     // Last day of period in "2006.01.02" format.
+	Users []UserCell // Configured users, used in absence form.
+	Data  []AbsenceRow
+}
+
+type AbsenceRow struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"user"`
+	LastName  string `json:"lastName"`
+	From      string `json:"from"` // First day of absence in "2006.01.02" format.
+	To        string `json:"to"`   // Last day of absence in "2006.01.02" format.
+	Type      string `json:"type"`
+	TypeName  string `json:"typeName"`
+	Comment   string `json:"comment"`
+	Author    string `json:"author"`
+	CreatedAt string `json:"createdAt"` // Time in "2006.01.02 15:04:05" format.
+}
+
+// Absence received from web API.
+// Type is one of "vacation", "sickLeave", "businessTrip".
+type AbsenceRequest struct {
+	Login   string
+	From    string // First day of absence in "2006.01.02" format.
+	To      string // Last day of absence in "2006.01.02" format.
+	Type    string
+	Comment string
+	Author  string
+}
+
+// Help receive accounted time report for a period from main service.
+type Report struct {
+	From        string // First day of period in "2006.01.02" format.
+	To          string // Last day of period in "2006.01.02" format.
+	UserQueue   []ReportRow
+	UserService []ReportRow
+	Customer    []ReportRow // LastName is empty, time summed for all users.
+}
+
+type ReportRow struct {
+	LastName string
+	Name     string
+	WorkTime int64
+	OverTime int64
+	Total    int64
+}
+
+// Functions used by HTTP server for interaction with main service.
+type Connectors struct {
+	TodayData          *TodayStatistic                                       // The structure to be filled in at the level of business logic.
+	GetCurrentWeekData func() (WeekStatistic, error)                         // Current week statistic.
+	GetLastWeekData    func() (WeekStatistic, error)                         // Last week statistic.
+	GetDayDetails      func(login, date string) (DayDetails, error)          // Accounted time entries of one user for one day.
+	GetReport          func(from, to string) (Report, error)                 // Accounted time by queue, service and customer for a period.
+	GetHistory         func(login, date string) (History, error)             // Change history of stored accounted time of one user for one day.
+	GetWDOList         func(from, to string) ([]DayOverride, error)          // Overridden days for a period.
+	SetWDO             func(wo DayOverride) error                            // Set workday override for day.
+	RemoveWDO          func(date string) error                               // Remove workday override for day.
+	GetAbsences        func(from, to string) (Absences, error)               // User absences for a period.
+	AddAbsence         func(a AbsenceRequest) (int64, error)                 // Add user absence, return its ID.
+	RemoveAbsence      func(id string) error                                 // Remove user absence by ID.
+	GetJobs            func() []JobStatus                                    // State of regular synchronisation jobs.
+	GetSyncState       func() SyncState                                      // State of synchronisation with OTRS, shown on every page.
+	StartResync        func(from, to, author string) (ResyncProgress, error) // Start re-sync of period from OTRS.
+	GetReconciliation  func() Reconciliation                                 // Report of the last reconciliation with OTRS.
+	GetResyncProgress  func() ResyncProgress                                 // Progress of the last re-sync.
+	APIToken           string                                                // Token required by protected API. Protected API is disabled if empty.
 }
 
 // Safe set data.
@@ -68,3 +206,60 @@ func (ts *TodayStatistic) Get() ([]TodayStatisticRow, time.Time) {
 
 	return ts.Data, ts.UpdateTime
 }
+
+// State of one regular job.
+type JobStatus struct {
+	Name        string `json:"name"`
+	Schedule    string `json:"schedule"`
+	Running     bool   `json:"running"`
+	LastStart   string `json:"lastStart"`   // Time in "2006.01.02 15:04:05" format, empty if job never started.
+	LastFinish  string `json:"lastFinish"`  // Time in "2006.01.02 15:04:05" format, empty if job never finished.
+	LastSuccess string `json:"lastSuccess"` // Time in "2006.01.02 15:04:05" format, empty if job never succeeded.
+	LastError   string `json:"lastError"`   // Empty if last attempt succeeded.
+	Failures    int    `json:"failures"`    // Failed attempts since the last success.
+	NextRun     string `json:"nextRun"`     // Time in "2006.01.02 15:04:05" format.
+}
+
+// State of synchronisation with OTRS.
+// Data is stale while regular refresh fails, e.g. OTRS DB is unavailable.
+type SyncState struct {
+	Stale      bool
+	LastUpdate string // Time of the last successful refresh in "2006.01.02 15:04:05" format, empty if never succeeded.
+	Error      string // Error of the last failed refresh.
+}
+
+// Progress of on-demand re-sync of accounted time from OTRS.
+type ResyncProgress struct {
+	Running    bool   `json:"running"`
+	From       string `json:"from"` // Day in "2006.01.02" format.
+	To         string `json:"to"`   // Day in "2006.01.02" format.
+	Author     string `json:"author"`
+	DoneDays   int    `json:"doneDays"`
+	TotalDays  int    `json:"totalDays"`
+	StartedAt  string `json:"startedAt"`  // Time in "2006.01.02 15:04:05" format.
+	FinishedAt string `json:"finishedAt"` // Time in "2006.01.02 15:04:05" format, empty while running.
+	Error      string `json:"error"`
+}
+
+// Report of comparison of stored accounted time with OTRS.
+type Reconciliation struct {
+	From      string              `json:"from"`      // Day in "2006.01.02" format.
+	To        string              `json:"to"`        // Day in "2006.01.02" format.
+	CheckedAt string              `json:"checkedAt"` // Time in "2006.01.02 15:04:05" format, empty if reconciliation never ran.
+	Applied   bool                `json:"applied"`   // True if mismatches are corrected.
+	Error     string              `json:"error"`
+	Data      []ReconciliationRow `json:"data"`
+}
+
+// Mismatched accounted time of one user for one day.
+// Missing is true if time is not stored at all.
+type ReconciliationRow struct {
+	Date           string `json:"date"` // Day in "2006.01.02" format.
+	Login          string `json:"login"`
+	LastName       string `json:"lastName"`
+	Missing        bool   `json:"missing"`
+	StoredWorkTime int64  `json:"storedWorkTime"`
+	StoredOverTime int64  `json:"storedOverTime"`
+	OTRSWorkTime   int64  `json:"otrsWorkTime"`
+	OTRSOverTime   int64  `json:"otrsOverTime"`
+}
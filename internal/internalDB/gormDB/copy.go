@@ -0,0 +1,106 @@
+package gormDB
+
+import (
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"gorm.io/gorm"
+)
+
+// Number of rows inserted by one query while copying data.
+const copyBatchSize = 500
+
+// Copy all data from one DB to another. Both providers must be created by this package and have schema of the same version.
+// Target DB must be empty, data is copied in one transaction.
+// History and absence rows get new IDs in the same order, so sequences of target DB stay valid.
+func Copy(srcProvider, dstProvider internalDB.Provider) error {
+	srcDB, ok := srcProvider.(DB)
+	if !ok {
+		return fmt.Errorf("unsupported source DB provider '%T'", srcProvider)
+	}
+	dstDB, ok := dstProvider.(DB)
+	if !ok {
+		return fmt.Errorf("unsupported target DB provider '%T'", dstProvider)
+	}
+	src, dst := srcDB.Instance, dstDB.Instance
+
+	srcVersion, err := schemaVersion(src)
+	if err != nil {
+		return err
+	}
+	dstVersion, err := schemaVersion(dst)
+	if err != nil {
+		return err
+	}
+	if srcVersion != dstVersion {
+		return fmt.Errorf("schema version mismatch: source '%v', target '%v'", srcVersion, dstVersion)
+	}
+
+	return dst.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&WorkdayOverride{}, &AccountedTime{}, &CategoryTime{}, &AccountedTimeHistory{}, &Absence{}} {
+			var count int64
+			err := tx.Model(model).Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return fmt.Errorf("target DB is not empty")
+			}
+		}
+
+		var woList []WorkdayOverride
+		err := copyTable(src, tx, &woList)
+		if err != nil {
+			return err
+		}
+		var atList []AccountedTime
+		err = copyTable(src, tx, &atList)
+		if err != nil {
+			return err
+		}
+		var ctList []CategoryTime
+		err = copyTable(src, tx, &ctList)
+		if err != nil {
+			return err
+		}
+
+		var absenceList []Absence
+		err = src.Order("id").Find(&absenceList).Error
+		if err != nil {
+			return err
+		}
+		for i := range absenceList {
+			absenceList[i].ID = 0
+		}
+		if len(absenceList) > 0 {
+			err = tx.CreateInBatches(&absenceList, copyBatchSize).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		var historyList []AccountedTimeHistory
+		err = src.Order("id").Find(&historyList).Error
+		if err != nil {
+			return err
+		}
+		for i := range historyList {
+			historyList[i].ID = 0
+		}
+		if len(historyList) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(&historyList, copyBatchSize).Error
+	})
+}
+
+// Read all rows of one table into rowList and insert them into target DB.
+func copyTable(src, dst *gorm.DB, rowList interface{}) error {
+	result := src.Find(rowList)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+	return dst.CreateInBatches(rowList, copyBatchSize).Error
+}
@@ -0,0 +1,53 @@
+package gormDB
+
+import (
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"gorm.io/gorm"
+	"sync"
+)
+
+// Implement internalDB Provider on top of gorm.
+// Schema and queries are shared by all supported DB engines, engine specific operations are provided by Backend.
+type DB struct {
+	Instance *gorm.DB
+	writeMx  *sync.Mutex // Serialises write transactions of service instance.
+}
+
+// DB engine specific operations used by schema migrations.
+type Backend interface {
+	// Create backup of all tables before migration from provided schema version.
+	// Return backup name for log or empty string if backup is not required (e.g. in-memory DB).
+	Backup(db *gorm.DB, version int64) (string, error)
+	// Lock migrations for other service instances until end of transaction.
+	LockMigrations(tx *gorm.DB) error
+}
+
+// Create or upgrade DB schema and return provider.
+// userList is used for migration of data stored before users were identified by OTRS login.
+func New(db *gorm.DB, backend Backend, userList []internalDB.User) (internalDB.Provider, error) {
+	err := migrate(db, backend, migrationList(userList))
+	if err != nil {
+		return nil, err
+	}
+	return DB{Instance: db, writeMx: &sync.Mutex{}}, nil
+}
+
+// Run write transaction.
+// Writes of one service instance are serialised, so concurrent synchronisations don't fail with sqlite "database is locked".
+func (db DB) write(fc func(tx *gorm.DB) error) error {
+	db.writeMx.Lock()
+	defer db.writeMx.Unlock()
+	return db.Instance.Transaction(fc)
+}
+
+// Wait for write transaction in progress and close DB connection.
+func (db DB) Close() error {
+	db.writeMx.Lock()
+	defer db.writeMx.Unlock()
+
+	sqlDB, err := db.Instance.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
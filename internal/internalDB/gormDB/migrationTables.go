@@ -0,0 +1,100 @@
+package gormDB
+
+import "time"
+
+// Tables as they were defined when migration of the version was written.
+// Migrations must use these copies instead of live models, so changes of models don't change applied migrations.
+// Copies are never changed, new schema change requires new copy and new migration.
+
+// workdayOverride table created by migration 1.
+type workdayOverrideV1 struct {
+	Day        int64 `gorm:"column:day;primaryKey"`
+	Overridden bool  `gorm:"column:overridden;not null"`
+}
+
+func (workdayOverrideV1) TableName() string {
+	return "workdayOverride"
+}
+
+// accountedTime table created by migration 1 and rekeyed by migration 3.
+type accountedTimeV1 struct {
+	Day      int64  `gorm:"column:day;primaryKey"`
+	Login    string `gorm:"column:login;primaryKey"`
+	WorkTime int64  `gorm:"column:workTime;not null"`
+	OverTime int64  `gorm:"column:overTime;not null"`
+}
+
+func (accountedTimeV1) TableName() string {
+	return "accountedTime"
+}
+
+// categoryTime table created by migration 2 and rekeyed by migration 3.
+type categoryTimeV2 struct {
+	Day      int64  `gorm:"column:day;primaryKey"`
+	Login    string `gorm:"column:login;primaryKey"`
+	Category string `gorm:"column:category;primaryKey"`
+	Name     string `gorm:"column:name;primaryKey"`
+	WorkTime int64  `gorm:"column:workTime;not null"`
+	OverTime int64  `gorm:"column:overTime;not null"`
+}
+
+func (categoryTimeV2) TableName() string {
+	return "categoryTime"
+}
+
+// accountedTimeHistory table created by migration 4.
+type accountedTimeHistoryV4 struct {
+	ID          int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	Day         int64     `gorm:"column:day;not null;index:idx_accountedTimeHistory_day_login"`
+	Login       string    `gorm:"column:login;not null;index:idx_accountedTimeHistory_day_login"`
+	IsNew       bool      `gorm:"column:isNew;not null"`
+	OldWorkTime int64     `gorm:"column:oldWorkTime;not null"`
+	OldOverTime int64     `gorm:"column:oldOverTime;not null"`
+	NewWorkTime int64     `gorm:"column:newWorkTime;not null"`
+	NewOverTime int64     `gorm:"column:newOverTime;not null"`
+	ChangedAt   time.Time `gorm:"column:changedAt;not null"`
+	Source      string    `gorm:"column:source;not null"`
+}
+
+func (accountedTimeHistoryV4) TableName() string {
+	return "accountedTimeHistory"
+}
+
+// workdayOverride columns added by migration 5.
+// Sqlite can't add column with non-constant default, so createdAt has constant default.
+type workdayOverrideV5 struct {
+	Day       int64     `gorm:"column:day;primaryKey"`
+	Type      string    `gorm:"column:type;not null;default:''"`
+	Reason    string    `gorm:"column:reason;not null;default:''"`
+	Author    string    `gorm:"column:author;not null;default:''"`
+	CreatedAt time.Time `gorm:"column:createdAt;not null;default:'1970-01-01 00:00:00'"`
+}
+
+func (workdayOverrideV5) TableName() string {
+	return "workdayOverride"
+}
+
+// workdayOverride column added by migration 6.
+type workdayOverrideV6 struct {
+	Norm int64 `gorm:"column:norm;not null;default:0"`
+}
+
+func (workdayOverrideV6) TableName() string {
+	return "workdayOverride"
+}
+
+// absence table created by migration 7.
+type absenceV7 struct {
+	ID        int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	Login     string    `gorm:"column:login;not null;index"`
+	FromDay   int64     `gorm:"column:fromDay;not null;index"`
+	ToDay     int64     `gorm:"column:toDay;not null;index"`
+	Type      string    `gorm:"column:type;not null"`
+	Comment   string    `gorm:"column:comment;not null"`
+	Author    string    `gorm:"column:author;not null"`
+	CreatedAt time.Time `gorm:"column:createdAt;not null"`
+}
+
+func (absenceV7) TableName() string {
+	return "absence"
+}
@@ -0,0 +1,246 @@
+package gormDB
+
+import (
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"gorm.io/gorm"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Table for store applied schema migrations.
+// Current schema version is the maximum stored version.
+type SchemaVersion struct {
+	Version     int64     `gorm:"column:version;primaryKey"`
+	Description string    `gorm:"column:description;not null"`
+	AppliedAt   time.Time `gorm:"column:appliedAt;not null"`
+}
+
+// TableName overrides the table name to `schema_version` (for gorm).
+func (SchemaVersion) TableName() string {
+	return "schema_version"
+}
+
+// One schema change. Migrations are applied in version order, each in own transaction.
+// DB files created before versioning have no schema_version table,
+// so up functions must check existing schema and skip already applied changes.
+type migration struct {
+	version     int64
+	description string
+	up          func(tx *gorm.DB) error
+}
+
+// Return ordered list of all schema migrations.
+// New migrations must be appended to the end of the list with next version number. Never change applied migrations.
+// Migrations use table copies from migrationTables.go, never live models.
+func migrationList(userList []internalDB.User) []migration {
+	return []migration{
+		{version: 1, description: "create workdayOverride and accountedTime tables", up: func(tx *gorm.DB) error {
+			return createMissingTables(tx, &workdayOverrideV1{}, &accountedTimeV1{})
+		}},
+		{version: 2, description: "create categoryTime table", up: func(tx *gorm.DB) error {
+			return createMissingTables(tx, &categoryTimeV2{})
+		}},
+		{version: 3, description: "identify users by OTRS login instead of last name", up: func(tx *gorm.DB) error {
+			return migrateLastNameToLogin(tx, userList)
+		}},
+		{version: 4, description: "create accountedTimeHistory table", up: func(tx *gorm.DB) error {
+			return createMissingTables(tx, &accountedTimeHistoryV4{})
+		}},
+		{version: 5, description: "add type, reason and author to workdayOverride", up: migrateWorkdayOverrideTypes},
+		{version: 6, description: "add norm to workdayOverride", up: migrateWorkdayOverrideNorm},
+		{version: 7, description: "create absence table", up: func(tx *gorm.DB) error {
+			return createMissingTables(tx, &absenceV7{})
+		}},
+	}
+}
+
+// Apply all pending migrations.
+// Backup of existing DB is created before first pending migration.
+// Refuse to work with schema created by newer version of service.
+// Several service instances can start with the same DB, every migration is applied once.
+func migrate(db *gorm.DB, backend Backend, migrationList []migration) error {
+	isNewDB, err := isEmptyDB(db)
+	if err != nil {
+		return err
+	}
+
+	err = db.AutoMigrate(&SchemaVersion{})
+	if err != nil {
+		return err
+	}
+	currentVersion, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	latestVersion := migrationList[len(migrationList)-1].version
+	if currentVersion > latestVersion {
+		return fmt.Errorf("%w: DB schema version '%v', supported version '%v'", internalDB.ErrNewerSchema, currentVersion, latestVersion)
+	}
+	if currentVersion == latestVersion {
+		return nil
+	}
+
+	if !isNewDB {
+		backupName, err := backend.Backup(db, currentVersion)
+		if err != nil {
+			return fmt.Errorf("DB backup before migration failed '%w'", err)
+		}
+		if backupName != "" {
+			log.Printf("DB backup created '%s'", backupName)
+		}
+	}
+
+	for _, m := range migrationList {
+		if m.version <= currentVersion {
+			continue
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			err := backend.LockMigrations(tx)
+			if err != nil {
+				return err
+			}
+			// Migration may be applied by other instance while waiting for lock.
+			version, err := schemaVersion(tx)
+			if err != nil {
+				return err
+			}
+			if version >= m.version {
+				return nil
+			}
+			err = m.up(tx)
+			if err != nil {
+				return err
+			}
+			return tx.Create(&SchemaVersion{Version: m.version, Description: m.description, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("DB migration to version '%v' (%s) failed '%w'", m.version, m.description, err)
+		}
+		log.Printf("DB migrated to version '%v' (%s)", m.version, m.description)
+	}
+
+	return nil
+}
+
+// Return current schema version. Return 0 if no migrations applied.
+func schemaVersion(db *gorm.DB) (int64, error) {
+	versionList := make([]SchemaVersion, 0, 1)
+	err := db.Model(&SchemaVersion{}).Order("version desc").Limit(1).Find(&versionList).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(versionList) == 0 {
+		return 0, nil
+	}
+	return versionList[0].Version, nil
+}
+
+// Check if DB has no tables (just created).
+func isEmptyDB(db *gorm.DB) (bool, error) {
+	tableList, err := db.Migrator().GetTables()
+	if err != nil {
+		return false, err
+	}
+	return len(tableList) == 0, nil
+}
+
+// Create tables which are not exists yet.
+func createMissingTables(tx *gorm.DB, modelList ...interface{}) error {
+	for _, model := range modelList {
+		if tx.Migrator().HasTable(model) {
+			continue
+		}
+		err := tx.Migrator().CreateTable(model)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rekey tables from user last name to OTRS login.
+// Rows of users missing in userList keep last name as login, so data is not lost.
+// Every row is mapped once, so last name of one user may be login of another.
+// Fail if last names or logins of configured users are not unique, such data can't be mapped.
+// Do nothing if tables are already keyed by login.
+func migrateLastNameToLogin(tx *gorm.DB, userList []internalDB.User) error {
+	loginExpr, loginArgs, err := lastNameToLoginExpr(userList)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range []struct {
+		name    string
+		model   interface{}
+		columns string
+	}{
+		{name: "accountedTime", model: &accountedTimeV1{}, columns: `day, %s, "workTime", "overTime"`},
+		{name: "categoryTime", model: &categoryTimeV2{}, columns: `day, %s, category, name, "workTime", "overTime"`},
+	} {
+		if !tx.Migrator().HasTable(table.name) || !tx.Migrator().HasColumn(table.name, "lastName") {
+			continue
+		}
+
+		// Primary key can't be changed in sqlite, so table is recreated.
+		oldName := table.name + "ByLastName"
+		err = tx.Migrator().RenameTable(table.name, oldName)
+		if err != nil {
+			return err
+		}
+		err = tx.Migrator().CreateTable(table.model)
+		if err != nil {
+			return err
+		}
+		err = tx.Exec(fmt.Sprintf(`insert into "%s" (%s) select %s from "%s"`,
+			table.name, fmt.Sprintf(table.columns, "login"), fmt.Sprintf(table.columns, loginExpr), oldName), loginArgs...).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Migrator().DropTable(oldName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return SQL expression which maps "lastName" column to login and its arguments.
+// Return error if the same last name or login belongs to several users.
+func lastNameToLoginExpr(userList []internalDB.User) (string, []interface{}, error) {
+	loginByLastName := make(map[string]string, len(userList))
+	lastNameByLogin := make(map[string]string, len(userList))
+	for _, user := range userList {
+		if login, ok := loginByLastName[user.LastName]; ok && login != user.Login {
+			return "", nil, fmt.Errorf("last name '%s' belongs to users '%s' and '%s', last names must be unique", user.LastName, login, user.Login)
+		}
+		if lastName, ok := lastNameByLogin[user.Login]; ok && lastName != user.LastName {
+			return "", nil, fmt.Errorf("login '%s' belongs to users '%s' and '%s', logins must be unique", user.Login, lastName, user.LastName)
+		}
+		loginByLastName[user.LastName] = user.Login
+		lastNameByLogin[user.Login] = user.LastName
+	}
+	if len(loginByLastName) == 0 {
+		return `"lastName"`, nil, nil
+	}
+
+	// Sorted for stable query text.
+	lastNameList := make([]string, 0, len(loginByLastName))
+	for lastName := range loginByLastName {
+		lastNameList = append(lastNameList, lastName)
+	}
+	sort.Strings(lastNameList)
+
+	var expr strings.Builder
+	args := make([]interface{}, 0, len(lastNameList)*2)
+	expr.WriteString(`case "lastName"`)
+	for _, lastName := range lastNameList {
+		expr.WriteString(" when ? then ?")
+		args = append(args, lastName, loginByLastName[lastName])
+	}
+	expr.WriteString(` else "lastName" end`)
+	return expr.String(), args, nil
+}
@@ -0,0 +1,101 @@
+package gormDB
+
+import (
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Table for store user absences (vacation, sick leave, business trip).
+type Absence struct {
+	ID        int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	Login     string    `gorm:"column:login;not null;index"`   // User OTRS login.
+	FromDay   int64     `gorm:"column:fromDay;not null;index"` // First day of absence, day number since 1970.01.01 .
+	ToDay     int64     `gorm:"column:toDay;not null;index"`   // Last day of absence, day number since 1970.01.01 .
+	Type      string    `gorm:"column:type;not null"`          // One of internalDB AbsenceType constants.
+	Comment   string    `gorm:"column:comment;not null"`       // Free text comment.
+	Author    string    `gorm:"column:author;not null"`        // Who created the absence.
+	CreatedAt time.Time `gorm:"column:createdAt;not null"`     // When the absence was created.
+}
+
+// TableName overrides the table name to `absence` (for gorm).
+func (Absence) TableName() string {
+	return "absence"
+}
+
+// Add absence of one user for [FromDay, ToDay] range. Return ID of added absence.
+// If type is unknown, login is empty or range is invalid, return ErrInvalidAbsence.
+func (db DB) AddAbsence(a internalDB.Absence) (int64, error) {
+	switch {
+	case a.Login == "":
+		return 0, fmt.Errorf("%w: user is not specified", internalDB.ErrInvalidAbsence)
+	case !internalDB.IsValidAbsenceType(a.Type):
+		return 0, fmt.Errorf("%w: unknown type '%s'", internalDB.ErrInvalidAbsence, a.Type)
+	case a.FromDay < 0 || a.FromDay > a.ToDay:
+		return 0, fmt.Errorf("%w: invalid range from '%v' to '%v'", internalDB.ErrInvalidAbsence, a.FromDay, a.ToDay)
+	}
+
+	row := Absence{
+		Login:     a.Login,
+		FromDay:   a.FromDay,
+		ToDay:     a.ToDay,
+		Type:      a.Type,
+		Comment:   a.Comment,
+		Author:    a.Author,
+		CreatedAt: time.Now(),
+	}
+	err := db.write(func(tx *gorm.DB) error {
+		return tx.Create(&row).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return row.ID, nil
+}
+
+// Remove absence by ID. If absence not exists, return ErrNotFound.
+func (db DB) RemoveAbsence(id int64) error {
+	return db.write(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", id).Delete(Absence{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: absence '%v'", internalDB.ErrNotFound, id)
+		}
+		return nil
+	})
+}
+
+// Get absences of all users which intersect [fromDay, toDay] range, ordered by start day.
+// If fromDay is after toDay, return ErrInvalidRange.
+func (db DB) GetAbsenceByDayRange(fromDay, toDay int64) ([]internalDB.Absence, error) {
+	if fromDay > toDay {
+		return nil, fmt.Errorf("%w: from '%v' is after to '%v'", internalDB.ErrInvalidRange, fromDay, toDay)
+	}
+
+	rowList := make([]Absence, 0, 16)
+	err := db.Instance.Model(&Absence{}).
+		Where(`"fromDay" <= ? and "toDay" >= ?`, toDay, fromDay).
+		Order(`"fromDay", login`).
+		Find(&rowList).Error
+	if err != nil {
+		return nil, err
+	}
+
+	absenceList := make([]internalDB.Absence, 0, len(rowList))
+	for _, row := range rowList {
+		absenceList = append(absenceList, internalDB.Absence{
+			ID:        row.ID,
+			Login:     row.Login,
+			FromDay:   row.FromDay,
+			ToDay:     row.ToDay,
+			Type:      row.Type,
+			Comment:   row.Comment,
+			Author:    row.Author,
+			CreatedAt: row.CreatedAt,
+		})
+	}
+	return absenceList, nil
+}
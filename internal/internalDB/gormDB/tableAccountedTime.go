@@ -0,0 +1,182 @@
+package gormDB
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Table for store accounted time.
+type AccountedTime struct {
+	Day      int64  `gorm:"column:day;primaryKey"`    // Day number since 1970.01.01 .
+	Login    string `gorm:"column:login;primaryKey"`  // User OTRS login.
+	WorkTime int64  `gorm:"column:workTime;not null"` // Main work time in minutes.
+	OverTime int64  `gorm:"column:overTime;not null"` // Overtime work time in minuets.
+}
+
+// TableName overrides the table name to `accountedTime` (for gorm).
+func (AccountedTime) TableName() string {
+	return "accountedTime"
+}
+
+// Add accounted time for one user by one day.
+// If data already exists, don't overwrite it and return ErrAlreadyExists.
+func (db DB) AddAccountedTime(login string, day, workTime, overTime int64, source string) error {
+	at := AccountedTime{
+		Day:      day,
+		Login:    login,
+		WorkTime: workTime,
+		OverTime: overTime,
+	}
+	return db.write(func(tx *gorm.DB) error {
+		// Do not add new data or rewrite old data if time already accounted.
+		result := tx.Clauses(accountedTimeConflict(true)).Create(&at)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: accounted time of '%s' for day '%v'", internalDB.ErrAlreadyExists, login, day)
+		}
+		return addAccountedTimeHistory(tx, AccountedTime{Day: day, Login: login}, at, true, source)
+	})
+}
+
+// Add accounted time for one user by one day.
+// If data already exists, overwrite it. Every change of stored time is recorded into change history.
+func (db DB) AddOrUpdateAccountedTime(login string, day, workTime, overTime int64, source string) error {
+	at := AccountedTime{
+		Day:      day,
+		Login:    login,
+		WorkTime: workTime,
+		OverTime: overTime,
+	}
+	return db.write(func(tx *gorm.DB) error {
+		return upsertAccountedTime(tx, at, source)
+	})
+}
+
+// Store accounted time of users and replace category data of days in one transaction.
+// Every key of ctByDay is a day to replace, empty list removes category data of the day.
+// Every change of stored time is recorded into change history.
+func (db DB) StoreSyncData(atList []internalDB.AccountedTime, ctByDay map[int64][]internalDB.CategoryTime, source string) error {
+	return db.write(func(tx *gorm.DB) error {
+		for _, at := range atList {
+			err := upsertAccountedTime(tx, AccountedTime{
+				Day:      at.Day,
+				Login:    at.Login,
+				WorkTime: at.WorkTime,
+				OverTime: at.OverTime,
+			}, source)
+			if err != nil {
+				return err
+			}
+		}
+		for day, ctList := range ctByDay {
+			err := replaceCategoryTime(tx, day, ctList)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Insert or update accounted time of one user for one day and record change into history.
+// Row is inserted first, so write lock is taken before old data is read and concurrent writers can't lose changes.
+func upsertAccountedTime(tx *gorm.DB, at AccountedTime, source string) error {
+	result := tx.Clauses(accountedTimeConflict(true)).Create(&at)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return addAccountedTimeHistory(tx, AccountedTime{Day: at.Day, Login: at.Login}, at, true, source)
+	}
+
+	old := AccountedTime{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("day = ? and login = ?", at.Day, at.Login).First(&old).Error
+	if err != nil {
+		return err
+	}
+	if old.WorkTime == at.WorkTime && old.OverTime == at.OverTime {
+		return nil
+	}
+	err = tx.Clauses(accountedTimeConflict(false)).Create(&at).Error
+	if err != nil {
+		return err
+	}
+	return addAccountedTimeHistory(tx, old, at, false, source)
+}
+
+// Return "on conflict" clause for accountedTime primary key.
+// Conflicting row is kept if doNothing is true, otherwise time is overwritten.
+func accountedTimeConflict(doNothing bool) clause.OnConflict {
+	onConflict := clause.OnConflict{Columns: []clause.Column{{Name: "day"}, {Name: "login"}}}
+	if doNothing {
+		onConflict.DoNothing = true
+	} else {
+		onConflict.DoUpdates = clause.AssignmentColumns([]string{"workTime", "overTime"})
+	}
+	return onConflict
+}
+
+// Get accounted data for for provided day (list of users and accounted time).
+// If day has no data, return empty slice.
+func (db DB) GetAccountedTimeByDay(day int64) ([]internalDB.AccountedTime, error) {
+	rowList := make([]AccountedTime, 0, 32)
+	err := db.Instance.Model(&AccountedTime{}).Where("day = ?", day).Find(&rowList).Error
+	if err != nil {
+		return nil, err
+	}
+	return toAccountedTimeList(rowList), nil
+}
+
+// Get accounted data (work time and overtime) for for provided day and login.
+// If data is not stored, return ErrNotFound.
+func (db DB) GetAccountedTimeByDayAndLogin(day int64, login string) (int64, int64, error) {
+	at := AccountedTime{}
+	err := db.Instance.Model(&AccountedTime{}).Where("day = ? and login = ?", day, login).First(&at).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, 0, fmt.Errorf("%w: accounted time of '%s' for day '%v'", internalDB.ErrNotFound, login, day)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	return at.WorkTime, at.OverTime, nil
+}
+
+// Get accounted data of listed users for every day in [fromDay, toDay] range in one query.
+// If loginList is empty, return data of all users. Days without stored data are not returned.
+// If fromDay is after toDay, return ErrInvalidRange.
+func (db DB) GetAccountedTimeByDayRange(fromDay, toDay int64, loginList []string) ([]internalDB.AccountedTime, error) {
+	if fromDay > toDay {
+		return nil, fmt.Errorf("%w: from '%v' is after to '%v'", internalDB.ErrInvalidRange, fromDay, toDay)
+	}
+
+	query := db.Instance.Model(&AccountedTime{}).Where("day >= ? and day <= ?", fromDay, toDay)
+	if len(loginList) > 0 {
+		query = query.Where("login in ?", loginList)
+	}
+	rowList := make([]AccountedTime, 0, 256)
+	err := query.Order("day, login").Find(&rowList).Error
+	if err != nil {
+		return nil, err
+	}
+	return toAccountedTimeList(rowList), nil
+}
+
+// Convert table rows to provider format.
+func toAccountedTimeList(rowList []AccountedTime) []internalDB.AccountedTime {
+	atList := make([]internalDB.AccountedTime, 0, len(rowList))
+	for _, row := range rowList {
+		atList = append(atList, internalDB.AccountedTime{
+			Day:      row.Day,
+			Login:    row.Login,
+			WorkTime: row.WorkTime,
+			OverTime: row.OverTime,
+		})
+	}
+	return atList
+}
@@ -0,0 +1,71 @@
+package gormDB
+
+import (
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Append-only table for store changes of accounted time.
+// Rows are never updated or deleted.
+type AccountedTimeHistory struct {
+	ID          int64     `gorm:"column:id;primaryKey;autoIncrement"`
+	Day         int64     `gorm:"column:day;not null;index:idx_accountedTimeHistory_day_login"`   // Day number since 1970.01.01 .
+	Login       string    `gorm:"column:login;not null;index:idx_accountedTimeHistory_day_login"` // User OTRS login.
+	IsNew       bool      `gorm:"column:isNew;not null"`                                          // True if time is stored for the first time.
+	OldWorkTime int64     `gorm:"column:oldWorkTime;not null"`                                    // Main work time in minutes before change.
+	OldOverTime int64     `gorm:"column:oldOverTime;not null"`                                    // Overtime work time in minuets before change.
+	NewWorkTime int64     `gorm:"column:newWorkTime;not null"`                                    // Main work time in minutes after change.
+	NewOverTime int64     `gorm:"column:newOverTime;not null"`                                    // Overtime work time in minuets after change.
+	ChangedAt   time.Time `gorm:"column:changedAt;not null"`                                      // Time of synchronisation.
+	Source      string    `gorm:"column:source;not null"`                                         // Who made the change.
+}
+
+// TableName overrides the table name to `accountedTimeHistory` (for gorm).
+func (AccountedTimeHistory) TableName() string {
+	return "accountedTimeHistory"
+}
+
+// Get all changes of accounted time for provided day and login, oldest first.
+// If time never changed, return empty slice.
+func (db DB) GetAccountedTimeHistory(day int64, login string) ([]internalDB.AccountedTimeChange, error) {
+	rowList := make([]AccountedTimeHistory, 0, 8)
+	err := db.Instance.Model(&AccountedTimeHistory{}).
+		Where("day = ? and login = ?", day, login).
+		Order("id").
+		Find(&rowList).Error
+	if err != nil {
+		return nil, err
+	}
+
+	changeList := make([]internalDB.AccountedTimeChange, 0, len(rowList))
+	for _, row := range rowList {
+		changeList = append(changeList, internalDB.AccountedTimeChange{
+			Day:         row.Day,
+			Login:       row.Login,
+			IsNew:       row.IsNew,
+			OldWorkTime: row.OldWorkTime,
+			OldOverTime: row.OldOverTime,
+			NewWorkTime: row.NewWorkTime,
+			NewOverTime: row.NewOverTime,
+			ChangedAt:   row.ChangedAt,
+			Source:      row.Source,
+		})
+	}
+	return changeList, nil
+}
+
+// Store one change of accounted time. Must be called in the same transaction as the change.
+func addAccountedTimeHistory(tx *gorm.DB, old, current AccountedTime, isNew bool, source string) error {
+	return tx.Create(&AccountedTimeHistory{
+		Day:         current.Day,
+		Login:       current.Login,
+		IsNew:       isNew,
+		OldWorkTime: old.WorkTime,
+		OldOverTime: old.OverTime,
+		NewWorkTime: current.WorkTime,
+		NewOverTime: current.OverTime,
+		ChangedAt:   time.Now(),
+		Source:      source,
+	}).Error
+}
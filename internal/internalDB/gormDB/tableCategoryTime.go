@@ -0,0 +1,73 @@
+package gormDB
+
+import (
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"gorm.io/gorm"
+)
+
+// Table for store accounted time aggregated by category (queue, service, customer company).
+type CategoryTime struct {
+	Day      int64  `gorm:"column:day;primaryKey"`      // Day number since 1970.01.01 .
+	Login    string `gorm:"column:login;primaryKey"`    // User OTRS login.
+	Category string `gorm:"column:category;primaryKey"` // Category type: queue, service or customer.
+	Name     string `gorm:"column:name;primaryKey"`     // Queue name, service name or customer company.
+	WorkTime int64  `gorm:"column:workTime;not null"`   // Main work time in minutes.
+	OverTime int64  `gorm:"column:overTime;not null"`   // Overtime work time in minuets.
+}
+
+// TableName overrides the table name to `categoryTime` (for gorm).
+func (CategoryTime) TableName() string {
+	return "categoryTime"
+}
+
+// Replace all category data for provided day.
+// Old data is removed and new data is stored in one transaction.
+func (db DB) ReplaceCategoryTime(day int64, ctList []internalDB.CategoryTime) error {
+	return db.write(func(tx *gorm.DB) error {
+		return replaceCategoryTime(tx, day, ctList)
+	})
+}
+
+// Replace all category data for provided day in transaction.
+func replaceCategoryTime(tx *gorm.DB, day int64, ctList []internalDB.CategoryTime) error {
+	rowList := make([]CategoryTime, 0, len(ctList))
+	for _, ct := range ctList {
+		rowList = append(rowList, CategoryTime{
+			Day:      day,
+			Login:    ct.Login,
+			Category: ct.Category,
+			Name:     ct.Name,
+			WorkTime: ct.WorkTime,
+			OverTime: ct.OverTime,
+		})
+	}
+
+	err := tx.Where("day = ?", day).Delete(CategoryTime{}).Error
+	if err != nil {
+		return err
+	}
+	if len(rowList) == 0 {
+		return nil
+	}
+	return tx.Create(&rowList).Error
+}
+
+// Get category data summed for every user, category and name in [fromDay, toDay] range.
+// If fromDay is after toDay, return ErrInvalidRange.
+func (db DB) GetCategoryTimeByDayRange(fromDay, toDay int64) ([]internalDB.CategoryTime, error) {
+	if fromDay > toDay {
+		return nil, fmt.Errorf("%w: from '%v' is after to '%v'", internalDB.ErrInvalidRange, fromDay, toDay)
+	}
+
+	ctList := make([]internalDB.CategoryTime, 0, 64)
+	err := db.Instance.Model(&CategoryTime{}).
+		Select(`login, category, name, sum("workTime") as work_time, sum("overTime") as over_time`).
+		Where("day >= ? and day <= ?", fromDay, toDay).
+		Group("login, category, name").
+		Scan(&ctList).Error
+	if err != nil {
+		return nil, err
+	}
+	return ctList, nil
+}
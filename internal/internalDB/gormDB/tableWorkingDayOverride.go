@@ -0,0 +1,181 @@
+package gormDB
+
+import (
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Table for store overridden days.
+// By default all Mondays, Tuesdays, Wednesdays, Thursdays and Fridays are workdays
+// and Saturdays and Sundays are not working days.
+// Overridden days are processed according to their type (holiday, transferred workday, etc.).
+type WorkdayOverride struct {
+	Day       int64     `gorm:"column:day;primaryKey"`                               // Day number since 1970.01.01 .
+	Type      string    `gorm:"column:type;not null;default:''"`                     // One of internalDB DayType constants.
+	Norm      int64     `gorm:"column:norm;not null;default:0"`                      // Work time norm in minutes, 0 for default norm.
+	Reason    string    `gorm:"column:reason;not null;default:''"`                   // Free text reason.
+	Author    string    `gorm:"column:author;not null;default:''"`                   // Who set the override last time.
+	CreatedAt time.Time `gorm:"column:createdAt;not null;default:CURRENT_TIMESTAMP"` // When the override was created.
+}
+
+// TableName overrides the table name to `workdayOverride` (for gorm).
+func (WorkdayOverride) TableName() string {
+	return "workdayOverride"
+}
+
+// Set overridden day. If day already overridden, replace type, norm, reason and author and keep creation time.
+// If type is unknown, return ErrInvalidDayType.
+func (db DB) SetWorkdayOverride(wo internalDB.WorkdayOverride) error {
+	return db.SetWorkdayOverrideList([]internalDB.WorkdayOverride{wo})
+}
+
+// Set list of overridden days in one transaction.
+// If any type is unknown, return ErrInvalidDayType and store nothing.
+func (db DB) SetWorkdayOverrideList(woList []internalDB.WorkdayOverride) error {
+	for _, wo := range woList {
+		if !internalDB.IsValidDayType(wo.Type) {
+			return fmt.Errorf("%w: '%s'", internalDB.ErrInvalidDayType, wo.Type)
+		}
+	}
+
+	return db.write(func(tx *gorm.DB) error {
+		for _, wo := range woList {
+			err := setWorkdayOverride(tx, wo)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Set overridden day in transaction.
+func setWorkdayOverride(tx *gorm.DB, wo internalDB.WorkdayOverride) error {
+	row := WorkdayOverride{
+		Day:       wo.Day,
+		Type:      wo.Type,
+		Norm:      wo.Norm,
+		Reason:    wo.Reason,
+		Author:    wo.Author,
+		CreatedAt: time.Now(),
+	}
+
+	// Check if day already overridden.
+	overridden, err := isWorkdayOverridden(tx, wo.Day)
+	if err != nil {
+		return err
+	}
+	if overridden {
+		return tx.Model(&WorkdayOverride{}).Where("day = ?", wo.Day).
+			Select("type", "norm", "reason", "author").Updates(&row).Error
+	}
+	return tx.Create(&row).Error
+}
+
+// Remove overridden day. If day not overridden do nothing.
+func (db DB) RemoveWorkdayOverride(day int64) error {
+	return db.write(func(tx *gorm.DB) error {
+		return tx.Where("day = ?", day).Delete(WorkdayOverride{}).Error
+	})
+}
+
+// Return list of all overridden days from specified range ordered by day.
+// If specified range not contain overridden days, return empty slice.
+// initialDay must be >= 0 and sequenceLen mast be > 0, otherwise ErrInvalidRange is returned.
+func (db DB) GetOverrideByDaySequence(initialDay, sequenceLen int64) ([]internalDB.WorkdayOverride, error) {
+	// Check provided initialDay and sequenceLen.
+	if initialDay < 0 {
+		return nil, fmt.Errorf("%w: ivalid initial day '%v'", internalDB.ErrInvalidRange, initialDay)
+	}
+	if sequenceLen < 1 {
+		return nil, fmt.Errorf("%w: ivalid sequence len '%v'", internalDB.ErrInvalidRange, sequenceLen)
+	}
+
+	overriddenDayList := make([]WorkdayOverride, 0, 16)
+	err := db.Instance.Where("day >= ? and day < ?", initialDay, initialDay+sequenceLen).Order("day").Find(&overriddenDayList).Error
+	if err != nil {
+		return nil, err
+	}
+
+	woList := make([]internalDB.WorkdayOverride, 0, len(overriddenDayList))
+	for _, overriddenDay := range overriddenDayList {
+		woList = append(woList, internalDB.WorkdayOverride{
+			Day:       overriddenDay.Day,
+			Type:      overriddenDay.Type,
+			Norm:      overriddenDay.Norm,
+			Reason:    overriddenDay.Reason,
+			Author:    overriddenDay.Author,
+			CreatedAt: overriddenDay.CreatedAt,
+		})
+	}
+
+	return woList, nil
+}
+
+// Check if workday overridden.
+func isWorkdayOverridden(db *gorm.DB, day int64) (bool, error) {
+	var count int64
+	err := db.Model(&WorkdayOverride{}).Where("day = ?", day).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Set type of days overridden before day types were introduced.
+// Overridden weekend became transferred workday and overridden weekday became holiday.
+// 1970.01.01 is Thursday, so (day + 3) % 7 is 0 for Monday and 5, 6 for weekend.
+func migrateWorkdayOverrideTypes(tx *gorm.DB) error {
+	for _, column := range []string{"Type", "Reason", "Author"} {
+		if tx.Migrator().HasColumn(&workdayOverrideV5{}, column) {
+			continue
+		}
+		err := tx.Migrator().AddColumn(&workdayOverrideV5{}, column)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Column has constant default, so existing days get migration time.
+	if !tx.Migrator().HasColumn(&workdayOverrideV5{}, "CreatedAt") {
+		err := tx.Migrator().AddColumn(&workdayOverrideV5{}, "CreatedAt")
+		if err != nil {
+			return err
+		}
+		err = tx.Exec(`update "workdayOverride" set "createdAt" = ?`, time.Now()).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	err := tx.Model(&workdayOverrideV5{}).Where("type = '' and (day + 3) % 7 >= 5").
+		Update("type", internalDB.DayTypeTransferredWorkday).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Model(&workdayOverrideV5{}).Where("type = ''").
+		Update("type", internalDB.DayTypeHoliday).Error
+	if err != nil {
+		return err
+	}
+	err = tx.Model(&workdayOverrideV5{}).Where("author = ''").
+		Update("author", "migration").Error
+	if err != nil {
+		return err
+	}
+
+	if tx.Migrator().HasColumn(&workdayOverrideV5{}, "overridden") {
+		return tx.Migrator().DropColumn(&workdayOverrideV5{}, "overridden")
+	}
+	return nil
+}
+
+// Add work time norm to overridden days. Existing days get default norm.
+func migrateWorkdayOverrideNorm(tx *gorm.DB) error {
+	if tx.Migrator().HasColumn(&workdayOverrideV6{}, "Norm") {
+		return nil
+	}
+	return tx.Migrator().AddColumn(&workdayOverrideV6{}, "Norm")
+}
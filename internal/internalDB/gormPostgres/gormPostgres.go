@@ -0,0 +1,72 @@
+package gormPostgres
+
+import (
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB/gormDB"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Key of PostgreSQL advisory lock, which serialises schema migrations of several service instances.
+const migrationLockKey = 727011
+
+// Initialise internal DB stored in PostgreSQL. Schema and migrations are the same as for sqlite.
+// userList is used for migration of data stored before users were identified by OTRS login.
+func NewDB(host, port, user, password, dbName, sslMode string, userList []internalDB.User) (internalDB.Provider, error) {
+	db, err := Open(host, port, user, password, dbName, sslMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create or upgrade DB schema.
+	return gormDB.New(db, backend{}, userList)
+}
+
+// Open connection to PostgreSQL DB without schema initialisation.
+func Open(host, port, user, password, dbName, sslMode string) (*gorm.DB, error) {
+	dbConnectionString := fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		host, port, user, password, dbName, sslMode,
+	)
+	db, err := gorm.Open(postgres.Open(dbConnectionString), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Implement gormDB Backend for PostgreSQL.
+type backend struct{}
+
+// Copy all tables into "backup_v<version>_<time>" schema of the same DB.
+func (b backend) Backup(db *gorm.DB, version int64) (string, error) {
+	schemaName := fmt.Sprintf("backup_v%v_%s", version, time.Now().Format("20060102150405"))
+	err := db.Transaction(func(tx *gorm.DB) error {
+		tableList, err := tx.Migrator().GetTables()
+		if err != nil {
+			return err
+		}
+		err = tx.Exec(fmt.Sprintf(`create schema "%s"`, schemaName)).Error
+		if err != nil {
+			return err
+		}
+		for _, table := range tableList {
+			err = tx.Exec(fmt.Sprintf(`create table "%s"."%s" as table "%s"`, schemaName, table, table)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return schemaName, nil
+}
+
+// Take transaction level advisory lock, so only one instance applies migration.
+func (b backend) LockMigrations(tx *gorm.DB) error {
+	return tx.Exec("select pg_advisory_xact_lock(?)", migrationLockKey).Error
+}
@@ -1,61 +1,81 @@
 package gromSqlite3
 
 import (
+	"fmt"
 	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB/gormDB"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"strings"
+	"time"
 )
 
-type DB struct {
-	Instance *gorm.DB
-}
+// DB file used if file name is not configured.
+const defaultFileName = "sqlite.db"
+
+// Time to wait for sqlite lock held by other process, in milliseconds.
+const busyTimeoutMs = 10000
 
-func NewDB(fileName string) (internalDB.Provider, error) {
+// Initialise internal DB stored in sqlite file.
+// userList is used for migration of data stored before users were identified by OTRS login.
+func NewDB(fileName string, userList []internalDB.User) (internalDB.Provider, error) {
 	// use default file name if not present.
 	if fileName == "" {
-		fileName = "sqlite.db"
-	}
-
-	// Initialise DB file (open or create).
-	db, err := openDB(fileName)
-	if err != nil {
-		return nil, err
+		fileName = defaultFileName
 	}
 
-	// Initialise DB schema.
-	db, err = initialiseDBSchema(db)
+	db, err := Open(fileName)
 	if err != nil {
 		return nil, err
 	}
 
-	DBProvider := DB{Instance: db}
-
-	return DBProvider, nil
+	// Create or upgrade DB schema.
+	return gormDB.New(db, backend{fileName: fileName}, userList)
 }
 
-func openDB(fileName string) (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(fileName), &gorm.Config{})
+// Open sqlite file (create if not exists) without schema initialisation.
+func Open(fileName string) (*gorm.DB, error) {
+	// use default file name if not present.
+	if fileName == "" {
+		fileName = defaultFileName
+	}
+
+	// Wait for locks of other processes (e.g. one-shot commands) instead of immediate "database is locked" error.
+	separator := "?"
+	if strings.Contains(fileName, "?") {
+		separator = "&"
+	}
+	db, err := gorm.Open(sqlite.Open(fmt.Sprint(fileName, separator, "_busy_timeout=", busyTimeoutMs)), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
 	return db, nil
 }
 
-func initialiseDBSchema(db *gorm.DB) (*gorm.DB, error) {
-	//err := db.AutoMigrate(&DayTimeAccounted{})
-	//if err != nil {
-	//	return nil, err
-	//}
+// Implement gormDB Backend for sqlite.
+type backend struct {
+	fileName string
+}
 
-	err := db.AutoMigrate(&WorkdayOverride{})
-	if err != nil {
-		return nil, err
+// Write consistent copy of DB into "<fileName>.v<version>.<time>.bak" file. Skip in-memory DB.
+func (b backend) Backup(db *gorm.DB, version int64) (string, error) {
+	if b.isInMemory() {
+		return "", nil
 	}
-
-	err = db.AutoMigrate(&AccountedTime{})
+	backupName := fmt.Sprintf("%s.v%v.%s.bak", b.fileName, version, time.Now().Format("20060102150405"))
+	err := db.Exec("vacuum into ?", backupName).Error
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	return backupName, nil
+}
 
-	return db, nil
+// Sqlite file is locked by every write transaction, so no additional lock is required.
+func (b backend) LockMigrations(tx *gorm.DB) error {
+	return nil
+}
+
+// Check if DB is not stored into file.
+func (b backend) isInMemory() bool {
+	return strings.Contains(b.fileName, ":memory:") || strings.Contains(b.fileName, "mode=memory")
 }
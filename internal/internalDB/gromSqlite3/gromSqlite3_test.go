@@ -0,0 +1,223 @@
+package gromSqlite3
+
+import (
+	"database/sql"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"path/filepath"
+	"testing"
+)
+
+// Schema created by service versions before schema migrations were introduced.
+var baselineSchema = []string{
+	"CREATE TABLE `workdayOverride` (`day` integer,`overridden` numeric NOT NULL,PRIMARY KEY (`day`))",
+	"CREATE TABLE `accountedTime` (`day` integer,`lastName` text,`workTime` integer NOT NULL,`overTime` integer NOT NULL,PRIMARY KEY (`day`,`lastName`))",
+}
+
+// Create sqlite file with baseline schema and data.
+func createBaselineDB(t *testing.T, fileName string) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	statementList := append(baselineSchema,
+		"INSERT INTO `workdayOverride` (`day`, `overridden`) VALUES (2, 1), (5, 1)",
+		"INSERT INTO `accountedTime` (`day`, `lastName`, `workTime`, `overTime`) VALUES (5, 'O''Brien', 480, 60), (5, 'Smith', 120, 0)",
+	)
+	for _, statement := range statementList {
+		_, err = db.Exec(statement)
+		if err != nil {
+			t.Fatalf("exec '%s': %v", statement, err)
+		}
+	}
+}
+
+func TestNewDBMigratesBaselineSchema(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "sqlite.db")
+	createBaselineDB(t, fileName)
+
+	db, err := NewDB(fileName, []internalDB.User{{Login: "obrien", LastName: "O'Brien"}})
+	if err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	defer db.Close()
+
+	// 1970.01.03 is Saturday, 1970.01.06 is Tuesday.
+	woList, err := db.GetOverrideByDaySequence(0, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(woList) != 2 {
+		t.Fatalf("expected 2 overridden days, got %+v", woList)
+	}
+	for i, expected := range []struct {
+		day     int64
+		dayType string
+	}{
+		{day: 2, dayType: internalDB.DayTypeTransferredWorkday},
+		{day: 5, dayType: internalDB.DayTypeHoliday},
+	} {
+		wo := woList[i]
+		if wo.Day != expected.day || wo.Type != expected.dayType || wo.Author != "migration" || wo.Norm != 0 {
+			t.Errorf("unexpected override %+v, expected day '%v' of type '%s'", wo, expected.day, expected.dayType)
+		}
+		if wo.CreatedAt.IsZero() || wo.CreatedAt.Year() < 2000 {
+			t.Errorf("createdAt of day '%v' is not backfilled: '%v'", wo.Day, wo.CreatedAt)
+		}
+	}
+
+	atList, err := db.GetAccountedTimeByDay(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	workTimeByLogin := make(map[string]int64, len(atList))
+	for _, at := range atList {
+		workTimeByLogin[at.Login] = at.WorkTime
+	}
+	if len(workTimeByLogin) != 2 || workTimeByLogin["obrien"] != 480 || workTimeByLogin["Smith"] != 120 {
+		t.Errorf("unexpected accounted time after migration %+v", atList)
+	}
+
+	// New data can be stored into migrated schema.
+	err = db.SetWorkdayOverride(internalDB.WorkdayOverride{Day: 6, Type: internalDB.DayTypeShortened, Norm: 420, Author: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AddAccountedTime("obrien", 6, 60, 0, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewDBCreatesSchema(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "sqlite.db")
+	db, err := NewDB(fileName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every table created by migrations matches current model.
+	err = db.SetWorkdayOverride(internalDB.WorkdayOverride{Day: 6, Type: internalDB.DayTypeShortened, Norm: 420, Reason: "pre-holiday", Author: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	woList, err := db.GetOverrideByDaySequence(6, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(woList) != 1 || woList[0].Norm != 420 || woList[0].Reason != "pre-holiday" || woList[0].CreatedAt.IsZero() {
+		t.Errorf("unexpected override %+v", woList)
+	}
+
+	// Update of overridden day keeps creation time.
+	err = db.SetWorkdayOverride(internalDB.WorkdayOverride{Day: 6, Type: internalDB.DayTypeHoliday, Author: "editor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updatedList, err := db.GetOverrideByDaySequence(6, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updatedList) != 1 || updatedList[0].Type != internalDB.DayTypeHoliday || updatedList[0].Author != "editor" ||
+		!updatedList[0].CreatedAt.Equal(woList[0].CreatedAt) {
+		t.Errorf("unexpected updated override %+v, created at '%v'", updatedList, woList[0].CreatedAt)
+	}
+
+	err = db.StoreSyncData([]internalDB.AccountedTime{{Day: 6, Login: "obrien", WorkTime: 60}},
+		map[int64][]internalDB.CategoryTime{6: {{Login: "obrien", Category: "queue", Name: "Support", WorkTime: 60}}}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AddOrUpdateAccountedTime("obrien", 6, 90, 0, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	changeList, err := db.GetAccountedTimeHistory(6, "obrien")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changeList) != 2 || !changeList[0].IsNew || changeList[1].OldWorkTime != 60 || changeList[1].NewWorkTime != 90 {
+		t.Errorf("unexpected history %+v", changeList)
+	}
+	ctList, err := db.GetCategoryTimeByDayRange(6, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ctList) != 1 || ctList[0].WorkTime != 60 {
+		t.Errorf("unexpected category time %+v", ctList)
+	}
+
+	_, err = db.AddAbsence(internalDB.Absence{Login: "obrien", FromDay: 5, ToDay: 7, Type: internalDB.AbsenceTypeVacation, Author: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	absenceList, err := db.GetAbsenceByDayRange(0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(absenceList) != 1 || absenceList[0].Login != "obrien" {
+		t.Errorf("unexpected absences %+v", absenceList)
+	}
+
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening migrated DB doesn't apply migrations again.
+	db, err = NewDB(fileName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewDBMigratesSwappedLastNames(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "sqlite.db")
+	createBaselineDB(t, fileName)
+
+	// Last name of every user is login of the other one.
+	db, err := NewDB(fileName, []internalDB.User{{Login: "Smith", LastName: "O'Brien"}, {Login: "O'Brien", LastName: "Smith"}})
+	if err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	defer db.Close()
+
+	atList, err := db.GetAccountedTimeByDay(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	workTimeByLogin := make(map[string]int64, len(atList))
+	for _, at := range atList {
+		workTimeByLogin[at.Login] = at.WorkTime
+	}
+	if len(workTimeByLogin) != 2 || workTimeByLogin["Smith"] != 480 || workTimeByLogin["O'Brien"] != 120 {
+		t.Errorf("unexpected accounted time after migration %+v", atList)
+	}
+}
+
+func TestNewDBRefusesDuplicateLastNames(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "sqlite.db")
+	createBaselineDB(t, fileName)
+
+	_, err := NewDB(fileName, []internalDB.User{{Login: "obrien", LastName: "O'Brien"}, {Login: "kobrien", LastName: "O'Brien"}})
+	if err == nil {
+		t.Fatal("migration with duplicate last names must fail")
+	}
+
+	// Failed migration keeps data keyed by last name, so it is migrated after config is fixed.
+	db, err := NewDB(fileName, []internalDB.User{{Login: "obrien", LastName: "O'Brien"}})
+	if err != nil {
+		t.Fatalf("migration failed: %v", err)
+	}
+	defer db.Close()
+	workTime, _, err := db.GetAccountedTimeByDayAndLogin(5, "obrien")
+	if err != nil || workTime != 480 {
+		t.Errorf("unexpected accounted time '%v' after migration, error '%v'", workTime, err)
+	}
+}
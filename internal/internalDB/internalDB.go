@@ -1,31 +1,180 @@
 package internalDB
 
+import (
+	"errors"
+	"time"
+)
+
+// Errors returned by Provider methods. Implementations may wrap them with details, so check with errors.Is.
+var (
+	ErrNotFound       = errors.New("data not found")
+	ErrAlreadyExists  = errors.New("data already exists")
+	ErrInvalidRange   = errors.New("invalid day range")
+	ErrNewerSchema    = errors.New("DB schema is newer than supported, update service")
+	ErrInvalidDayType = errors.New("invalid day type")
+	ErrInvalidAbsence = errors.New("invalid absence")
+)
+
+// Types of overridden days.
+const (
+	DayTypeHoliday            = "holiday"            // Public holiday, day off.
+	DayTypeTransferredWorkday = "transferredWorkday" // Weekend day transferred to workday.
+	DayTypeShortened          = "shortened"          // Shortened pre-holiday workday.
+	DayTypeCorporateDayOff    = "corporateDayOff"    // Day off declared by company.
+)
+
+// Types of user absences.
+const (
+	AbsenceTypeVacation     = "vacation"
+	AbsenceTypeSickLeave    = "sickLeave"
+	AbsenceTypeBusinessTrip = "businessTrip"
+)
+
 // Declare set of methods for interaction with internal DB.
-// Day numbers are counted since 1970.01.01 .
+// Day numbers are counted since 1970.01.01 . Users are identified by OTRS login.
+// Every method returns storage errors, so broken DB is not shown as empty data.
 type Provider interface {
 
-	// Set new overridden day. If day already overridden do nothing.
-	SetWorkdayOverride(day int64)
+	// Set overridden day. If day already overridden, replace type, reason and author.
+	// If type is unknown, return ErrInvalidDayType.
+	SetWorkdayOverride(wo WorkdayOverride) error
+	// Set list of overridden days in one transaction, nothing is stored if any day is not stored.
+	// If any type is unknown, return ErrInvalidDayType.
+	SetWorkdayOverrideList(woList []WorkdayOverride) error
 	// Remove overridden day. If day not overridden do nothing.
-	RemoveWorkdayOverride(day int64)
-	// Return list of all overridden days from specified range.
+	RemoveWorkdayOverride(day int64) error
+	// Return list of all overridden days from specified range ordered by day.
 	// If specified range not contain overridden days, return empty slice.
-	// initialDay must be >= 0 and sequenceLen mast be > 0.
-	GetOverrideByDaySequence(initialDay, sequenceLen int64) ([]int64, error)
+	// initialDay must be >= 0 and sequenceLen mast be > 0, otherwise ErrInvalidRange is returned.
+	GetOverrideByDaySequence(initialDay, sequenceLen int64) ([]WorkdayOverride, error)
 
 	// Add accounted time for one user by one day.
-	// If data already exists, don't overwrite it.
-	AddAccountedTime(lastName string, day, workTime, overTime int64)
+	// If data already exists, don't overwrite it and return ErrAlreadyExists.
+	// Source describes who made the change and is stored into change history.
+	AddAccountedTime(login string, day, workTime, overTime int64, source string) error
 	// Add accounted time for one user by one day.
-	AddOrUpdateAccountedTime(lastName string, day, workTime, overTime int64)
+	// If data already exists, overwrite it. Every change of stored time is recorded into change history.
+	AddOrUpdateAccountedTime(login string, day, workTime, overTime int64, source string) error
 	// Get accounted data for for provided day (list of users and accounted time).
-	GetAccountedTimeByDay(day int64) []AccountedTime
-	// Get accounted data for for provided day and last name.
-	GetAccountedTimeByDayAndLastname(day int64, lastName string) (int64, int64)
+	// If day has no data, return empty slice.
+	GetAccountedTimeByDay(day int64) ([]AccountedTime, error)
+	// Get accounted data (work time and overtime) for for provided day and login.
+	// If data is not stored, return ErrNotFound.
+	GetAccountedTimeByDayAndLogin(day int64, login string) (int64, int64, error)
+	// Get accounted data of listed users for every day in [fromDay, toDay] range in one query.
+	// If loginList is empty, return data of all users. Days without stored data are not returned.
+	// If fromDay is after toDay, return ErrInvalidRange.
+	GetAccountedTimeByDayRange(fromDay, toDay int64, loginList []string) ([]AccountedTime, error)
+	// Get all changes of accounted time for provided day and login, oldest first.
+	// If time never changed, return empty slice.
+	GetAccountedTimeHistory(day int64, login string) ([]AccountedTimeChange, error)
+
+	// Add absence of one user for [FromDay, ToDay] range. Return ID of added absence.
+	// If type is unknown, login is empty or range is invalid, return ErrInvalidAbsence.
+	AddAbsence(a Absence) (int64, error)
+	// Remove absence by ID. If absence not exists, return ErrNotFound.
+	RemoveAbsence(id int64) error
+	// Get absences of all users which intersect [fromDay, toDay] range, ordered by start day.
+	// If fromDay is after toDay, return ErrInvalidRange.
+	GetAbsenceByDayRange(fromDay, toDay int64) ([]Absence, error)
+
+	// Store accounted time of users and replace category data of days in one transaction.
+	// Every key of ctByDay is a day to replace, empty list removes category data of the day.
+	// Every change of stored time is recorded into change history.
+	StoreSyncData(atList []AccountedTime, ctByDay map[int64][]CategoryTime, source string) error
+
+	// Replace all category data for provided day.
+	ReplaceCategoryTime(day int64, ctList []CategoryTime) error
+	// Get category data summed for every user, category and name in [fromDay, toDay] range.
+	// If fromDay is after toDay, return ErrInvalidRange.
+	GetCategoryTimeByDayRange(fromDay, toDay int64) ([]CategoryTime, error)
+
+	// Close DB connection. Write started before Close is finished first.
+	Close() error
+}
+
+// Format for store and return overridden day.
+// Type is one of DayType constants, CreatedAt is set by provider.
+// Norm is work time norm of the day in minutes, 0 means default norm for day type.
+type WorkdayOverride struct {
+	Day       int64 // Day number since 1970.01.01 .
+	Type      string
+	Norm      int64
+	Reason    string
+	Author    string
+	CreatedAt time.Time
+}
+
+// Check if day type is known.
+func IsValidDayType(dayType string) bool {
+	switch dayType {
+	case DayTypeHoliday, DayTypeTransferredWorkday, DayTypeShortened, DayTypeCorporateDayOff:
+		return true
+	}
+	return false
+}
+
+// Check if day of provided type is workday.
+func IsWorkdayType(dayType string) bool {
+	return dayType == DayTypeTransferredWorkday || dayType == DayTypeShortened
+}
+
+// Configured user. Used for migration of data stored before users were identified by OTRS login.
+type User struct {
+	Login    string
+	LastName string
+}
+
+// Format for store and return user absence.
+// Type is one of AbsenceType constants, ID and CreatedAt are set by provider.
+type Absence struct {
+	ID        int64
+	Login     string
+	FromDay   int64 // First day of absence, day number since 1970.01.01 .
+	ToDay     int64 // Last day of absence, day number since 1970.01.01 .
+	Type      string
+	Comment   string
+	Author    string
+	CreatedAt time.Time
+}
+
+// Check if absence type is known.
+func IsValidAbsenceType(absenceType string) bool {
+	switch absenceType {
+	case AbsenceTypeVacation, AbsenceTypeSickLeave, AbsenceTypeBusinessTrip:
+		return true
+	}
+	return false
 }
 
 // Format for return accounted time.
 type AccountedTime struct {
+	Day      int64 // Day number since 1970.01.01 .
+	Login    string
+	WorkTime int64 // Main work time in minutes.
+	OverTime int64 // Overtime work time in minuets.
+}
+
+// Format for return one change of accounted time.
+// Old time is zero when data is stored for the first time (IsNew is true).
+type AccountedTimeChange struct {
+	Day         int64 // Day number since 1970.01.01 .
+	Login       string
+	IsNew       bool
+	OldWorkTime int64
+	OldOverTime int64
+	NewWorkTime int64
+	NewOverTime int64
+	ChangedAt   time.Time // Time of synchronisation.
+	Source      string    // Who made the change, e.g. regular synchronisation.
+}
+
+// Format for store and return accounted time aggregated by category.
+// Category is one of "queue", "service" or "customer", Name is queue name, service name or customer company.
+type CategoryTime struct {
+	Login    string
+	Category string
+	Name     string
 	WorkTime int64 // Main work time in minutes.
 	OverTime int64 // Overtime work time in minuets.
 }
@@ -0,0 +1,367 @@
+package fake
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs"
+	"gopkg.in/yaml.v2"
+	"hash/fnv"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const dateFormatLayout = "2006.01.02" // Date format layout used in fixture files.
+
+// Values used by random generator.
+var (
+	queueList    = []string{"Postmaster", "Raw", "Junk", "Misc", "Support::L1", "Support::L2"}
+	serviceList  = []string{"", "Mail", "Network", "Workplace", "Telephony"}
+	customerList = []string{"", "ООО Ромашка", "АО Василёк", "ИП Иванов"}
+	titleList    = []string{"Не работает почта", "Нет доступа к сети", "Замена картриджа", "Настройка телефона", "Консультация"}
+	stateList    = []string{otrs.StateTypeNew, otrs.StateTypeOpen, otrs.StateTypeOpen, otrs.StateTypeClosed, otrs.StateTypePendingReminder, otrs.StateTypeMerged}
+)
+
+// Implement otrs Provider.
+// Serve data from fixture file or generate random data without any external DB.
+// Random data is stable for the same seed, user and day.
+type Fake struct {
+	UserList []string // User logins.
+	States   otrs.StateClassification
+	Seed     int64
+	Fixture  *Fixture // Nil if data is generated.
+}
+
+// Fixture file content.
+type Fixture struct {
+	Entries       []FixtureEntry  `yaml:"Entries" json:"Entries"`
+	LockedTickets []FixtureTicket `yaml:"LockedTickets" json:"LockedTickets"`
+}
+
+// One time accounting entry.
+// Day is set by Date (in "2006.01.02" format) or by DayOffset from today if Date is empty.
+type FixtureEntry struct {
+	Login        string `yaml:"Login" json:"Login"`
+	Date         string `yaml:"Date" json:"Date"`
+	DayOffset    int    `yaml:"DayOffset" json:"DayOffset"`
+	TicketNumber string `yaml:"TicketNumber" json:"TicketNumber"`
+	Title        string `yaml:"Title" json:"Title"`
+	Queue        string `yaml:"Queue" json:"Queue"`
+	Service      string `yaml:"Service" json:"Service"`
+	Customer     string `yaml:"Customer" json:"Customer"`
+	ArticleID    int    `yaml:"ArticleID" json:"ArticleID"`
+	Minutes      int    `yaml:"Minutes" json:"Minutes"`
+	IsOverTime   bool   `yaml:"IsOverTime" json:"IsOverTime"`
+}
+
+// Ticket locked by user, used for today ticket statistic.
+type FixtureTicket struct {
+	Login     string `yaml:"Login" json:"Login"`
+	StateType string `yaml:"StateType" json:"StateType"`
+}
+
+// Entry with resolved day.
+type entry struct {
+	day time.Time
+	FixtureEntry
+}
+
+// Initialise and return fake OTRS connector.
+// If fixtureFile is empty, data is generated with provided seed.
+// Fixture file format (YAML or JSON) is selected by file extension.
+func NewProvider(fixtureFile string, seed int64, userList []string, states otrs.StateClassification) (Fake, error) {
+	// Return error if empty slice provided.
+	if len(userList) < 1 {
+		return Fake{}, errors.New("user list must contain at least one user")
+	}
+	err := states.Validate()
+	if err != nil {
+		return Fake{}, err
+	}
+
+	f := Fake{UserList: userList, States: states, Seed: seed}
+	if fixtureFile == "" {
+		return f, nil
+	}
+
+	f.Fixture, err = readFixture(fixtureFile)
+	if err != nil {
+		return Fake{}, err
+	}
+	return f, nil
+}
+
+// Read and unmarshal fixture file.
+func readFixture(fileName string) (*Fixture, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture Fixture
+	switch filepath.Ext(fileName) {
+	case ".json":
+		err = json.Unmarshal(data, &fixture)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &fixture)
+	default:
+		return nil, fmt.Errorf("unknown fixture file format '%s'", fileName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Check dates before usage.
+	for _, e := range fixture.Entries {
+		if e.Date == "" {
+			continue
+		}
+		_, err = time.ParseInLocation(dateFormatLayout, e.Date, time.Local)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &fixture, nil
+}
+
+// Get today data.
+func (f Fake) GetTodayData(ctx context.Context) ([]otrs.DayStatisticRow, error) {
+	today := truncateToDay(time.Now())
+	rowIndex := make(map[string]int, len(f.UserList))
+	otrsData := f.emptyRows(rowIndex)
+
+	for _, e := range f.entries(today, today.AddDate(0, 0, 1)) {
+		otrsData[rowIndex[e.Login]].WorkTime += e.Minutes
+	}
+
+	for _, t := range f.lockedTickets() {
+		i, ok := rowIndex[t.Login]
+		if !ok {
+			continue
+		}
+		otrsData[i].LockedTicketCount++
+		if !f.States.IsClosed(t.StateType) {
+			otrsData[i].NotClosedTicketCount++
+		}
+		if f.States.IsOpen(t.StateType) {
+			otrsData[i].OpenTicketCount++
+		}
+	}
+
+	return otrsData, nil
+}
+
+// Get accounted work time and overtime for specified day.
+func (f Fake) GetCustomDayData(ctx context.Context, day time.Time) ([]otrs.DayStatisticRow, error) {
+	dayStart := truncateToDay(day)
+	rowIndex := make(map[string]int, len(f.UserList))
+	otrsData := f.emptyRows(rowIndex)
+
+	for _, e := range f.entries(dayStart, dayStart.AddDate(0, 0, 1)) {
+		if e.IsOverTime {
+			otrsData[rowIndex[e.Login]].OverTime += e.Minutes
+		} else {
+			otrsData[rowIndex[e.Login]].WorkTime += e.Minutes
+		}
+	}
+
+	return otrsData, nil
+}
+
+// Get accounted work time and overtime per user for every day in [from, to) period.
+func (f Fake) GetRangeData(ctx context.Context, from, to time.Time) ([]otrs.RangeStatisticRow, error) {
+	type key struct {
+		day   time.Time
+		login string
+	}
+	rowIndex := make(map[key]int)
+	otrsData := make([]otrs.RangeStatisticRow, 0, 64)
+	for _, e := range f.entries(truncateToDay(from), truncateToDay(to)) {
+		k := key{day: e.day, login: e.Login}
+		i, ok := rowIndex[k]
+		if !ok {
+			i = len(otrsData)
+			rowIndex[k] = i
+			otrsData = append(otrsData, otrs.RangeStatisticRow{Day: e.day, Login: e.Login})
+		}
+		if e.IsOverTime {
+			otrsData[i].OverTime += e.Minutes
+		} else {
+			otrsData[i].WorkTime += e.Minutes
+		}
+	}
+
+	return otrsData, nil
+}
+
+// Get accounted time entries of one user for specified day.
+func (f Fake) GetAccountedTimeDetails(ctx context.Context, login string, day time.Time) ([]otrs.AccountedTimeEntry, error) {
+	dayStart := truncateToDay(day)
+	entryList := make([]otrs.AccountedTimeEntry, 0, 16)
+	for _, e := range f.entries(dayStart, dayStart.AddDate(0, 0, 1)) {
+		if e.Login != login {
+			continue
+		}
+		entryList = append(entryList, otrs.AccountedTimeEntry{
+			TicketNumber: e.TicketNumber,
+			Title:        e.Title,
+			Queue:        e.Queue,
+			ArticleID:    e.ArticleID,
+			Minutes:      e.Minutes,
+			IsOverTime:   e.IsOverTime,
+		})
+	}
+
+	return entryList, nil
+}
+
+// Get accounted time per user and category for every day in [from, to) period.
+func (f Fake) GetCategoryData(ctx context.Context, from, to time.Time) ([]otrs.CategoryStatisticRow, error) {
+	entryList := f.entries(truncateToDay(from), truncateToDay(to))
+	groupList := make([]otrs.TicketGroupRow, 0, len(entryList))
+	for _, e := range entryList {
+		group := otrs.TicketGroupRow{
+			Day:      e.day,
+			Login:    e.Login,
+			Queue:    e.Queue,
+			Service:  e.Service,
+			Customer: e.Customer,
+		}
+		if e.IsOverTime {
+			group.OverTime = e.Minutes
+		} else {
+			group.WorkTime = e.Minutes
+		}
+		groupList = append(groupList, group)
+	}
+
+	return otrs.AggregateByCategory(groupList), nil
+}
+
+// Nothing to close.
+func (f Fake) Stop() error {
+	return nil
+}
+
+// Return rows for all configured users ordered by login and fill map from login to row index.
+func (f Fake) emptyRows(rowIndex map[string]int) []otrs.DayStatisticRow {
+	nameList := append([]string{}, f.UserList...)
+	sort.Strings(nameList)
+
+	otrsData := make([]otrs.DayStatisticRow, 0, len(nameList))
+	for _, login := range nameList {
+		if _, ok := rowIndex[login]; ok {
+			continue
+		}
+		rowIndex[login] = len(otrsData)
+		otrsData = append(otrsData, otrs.DayStatisticRow{Login: login})
+	}
+	return otrsData
+}
+
+// Return time accounting entries of configured users in [from, to) period.
+func (f Fake) entries(from, to time.Time) []entry {
+	entryList := make([]entry, 0, 64)
+	if f.Fixture == nil {
+		for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+			for _, login := range f.UserList {
+				entryList = append(entryList, f.generateEntries(login, day)...)
+			}
+		}
+		return entryList
+	}
+
+	today := truncateToDay(time.Now())
+	for _, fe := range f.Fixture.Entries {
+		if !f.isConfiguredUser(fe.Login) {
+			continue
+		}
+		day := today.AddDate(0, 0, fe.DayOffset)
+		if fe.Date != "" {
+			// Date is checked on fixture read.
+			day, _ = time.ParseInLocation(dateFormatLayout, fe.Date, time.Local)
+		}
+		if day.Before(from) || !day.Before(to) {
+			continue
+		}
+		entryList = append(entryList, entry{day: day, FixtureEntry: fe})
+	}
+	return entryList
+}
+
+// Return tickets locked by configured users.
+func (f Fake) lockedTickets() []FixtureTicket {
+	if f.Fixture != nil {
+		return f.Fixture.LockedTickets
+	}
+
+	ticketList := make([]FixtureTicket, 0, 64)
+	for _, login := range f.UserList {
+		r := f.random(login, time.Time{})
+		for i := r.Intn(8); i > 0; i-- {
+			ticketList = append(ticketList, FixtureTicket{Login: login, StateType: stateList[r.Intn(len(stateList))]})
+		}
+	}
+	return ticketList
+}
+
+// Generate random time accounting entries of user for one day.
+// Days off contain entries rarely and mostly as overtime.
+func (f Fake) generateEntries(login string, day time.Time) []entry {
+	r := f.random(login, day)
+	isDayOff := day.Weekday() == time.Saturday || day.Weekday() == time.Sunday
+
+	count := 2 + r.Intn(7)
+	if isDayOff {
+		count = 0
+		if r.Intn(5) == 0 {
+			count = 1 + r.Intn(2)
+		}
+	}
+
+	entryList := make([]entry, 0, count)
+	for i := 0; i < count; i++ {
+		entryList = append(entryList, entry{
+			day: day,
+			FixtureEntry: FixtureEntry{
+				Login:        login,
+				TicketNumber: fmt.Sprintf("%s%08d", day.Format("20060102"), 10000000+r.Intn(90000000)),
+				Title:        titleList[r.Intn(len(titleList))],
+				Queue:        queueList[r.Intn(len(queueList))],
+				Service:      serviceList[r.Intn(len(serviceList))],
+				Customer:     customerList[r.Intn(len(customerList))],
+				ArticleID:    1 + r.Intn(100000),
+				Minutes:      5 * (3 + r.Intn(16)),
+				IsOverTime:   isDayOff || r.Intn(10) == 0,
+			},
+		})
+	}
+	return entryList
+}
+
+// Return random generator seeded by provider seed, user and day.
+func (f Fake) random(login string, day time.Time) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(login))
+	h.Write([]byte(day.Format(dateFormatLayout)))
+	return rand.New(rand.NewSource(f.Seed ^ int64(h.Sum64())))
+}
+
+// Check if user is configured.
+func (f Fake) isConfiguredUser(login string) bool {
+	for _, user := range f.UserList {
+		if user == login {
+			return true
+		}
+	}
+	return false
+}
+
+// Return start of the day in the same location.
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,611 @@
+package genericInterface
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	dateTimeFormatLayout = "2006-01-02 15:04:05" // Date format layout used in OTRS web service requests.
+	ticketGetBatchSize   = 50                    // Maximum number of tickets requested by one TicketGet call.
+	ticketSearchLimit    = 10000                 // Maximum number of tickets returned by one TicketSearch call.
+	requestTimeout       = time.Minute           // Timeout for one web service request.
+
+	// Operation routes. Web service must map them with POST request method.
+	sessionCreateRoute = "/Session"
+	ticketSearchRoute  = "/TicketSearch"
+	ticketGetRoute     = "/TicketGet"
+)
+
+// Ticket lock types counted as locked, same as any lock except "unlock" in SQL providers.
+var lockedLockList = []string{"lock", "tmp_lock"}
+
+// Search result reached the limit, so it may be truncated.
+var errSearchLimit = errors.New("ticket search result reached the limit")
+
+// Implement otrs Provider.
+// Get data through OTRS GenericInterface REST web service instead of direct DB access.
+// Accounted time is read from the "TimeUnit" attribute of ticket articles,
+// overtime mark is read from the article dynamic field with name OvertimeField.
+// Limitations compared to SQL providers:
+//   - web service doesn't return time accounted without article, such time is not counted;
+//   - every ticket with articles created in requested period is read with all its articles.
+type GenericInterface struct {
+	Client        *http.Client
+	URL           string         // Web service URL, e.g. https://otrs.local/otrs/nph-genericinterface.pl/Webservice/TimeAccounting .
+	UserLogin     string         // Agent login used for session creation.
+	Password      string         // Agent password used for session creation.
+	OvertimeField string         // Name of article dynamic field which marks overtime.
+	Users         map[int]string // OTRS user ID to login.
+	States        otrs.StateClassification
+	session       *session // Shared between copies of provider.
+	searchLimit   int      // Maximum number of tickets returned by one TicketSearch call, ticketSearchLimit if 0.
+}
+
+// Current web service session.
+type session struct {
+	id string
+	mx sync.Mutex
+}
+
+// Error returned by OTRS web service.
+type serviceError struct {
+	ErrorCode    string
+	ErrorMessage string
+}
+
+// Ticket data returned by TicketGet operation.
+type ticket struct {
+	TicketID     flexNumber
+	TicketNumber string
+	Title        string
+	Queue        string
+	Service      string
+	CustomerID   string
+	Lock         string
+	StateType    string
+	OwnerID      flexNumber
+	Article      []article
+}
+
+// Article data returned by TicketGet operation.
+type article struct {
+	ArticleID    flexNumber
+	CreateBy     flexNumber
+	CreateTime   string
+	TimeUnit     flexNumber
+	DynamicField []dynamicField
+
+	ticket     ticket    // Ticket which article belongs to. Filled by getArticles.
+	createTime time.Time // Parsed CreateTime. Filled by getArticles.
+}
+
+// Dynamic field value returned by TicketGet operation.
+type dynamicField struct {
+	Name  string
+	Value json.RawMessage
+}
+
+// OTRS returns numbers either as JSON numbers or as strings.
+type flexNumber float64
+
+func (n *flexNumber) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	var f float64
+	err := json.Unmarshal([]byte(s), &f)
+	if err != nil {
+		return err
+	}
+	*n = flexNumber(f)
+	return nil
+}
+
+// Initialise and return OTRS web service connector.
+// users maps OTRS user ID to login of users for whom data is collected.
+func NewProvider(ctx context.Context, url, webService, userLogin, password, overtimeField string, users map[int]string, states otrs.StateClassification) (GenericInterface, error) {
+	// Return error if empty map provided.
+	if len(users) < 1 {
+		return GenericInterface{}, errors.New("user list must contain at least one user with OTRS user ID")
+	}
+	if webService == "" {
+		return GenericInterface{}, errors.New("web service name must be specified")
+	}
+	if overtimeField == "" {
+		return GenericInterface{}, errors.New("overtime dynamic field name must be specified")
+	}
+	err := states.Validate()
+	if err != nil {
+		return GenericInterface{}, err
+	}
+
+	gi := GenericInterface{
+		Client:        &http.Client{Timeout: requestTimeout},
+		URL:           fmt.Sprintf("%s/nph-genericinterface.pl/Webservice/%s", strings.TrimSuffix(url, "/"), webService),
+		UserLogin:     userLogin,
+		Password:      password,
+		OvertimeField: overtimeField,
+		Users:         users,
+		States:        states,
+		session:       &session{},
+	}
+
+	// Check credentials and web service availability.
+	// Provider is usable on error, session is created again on next call.
+	_, err = gi.sessionID(ctx, true)
+	if err != nil {
+		return gi, fmt.Errorf("%w: %v", otrs.ErrUnavailable, err)
+	}
+
+	return gi, nil
+}
+
+// Get today data from OTRS web service.
+func (gi GenericInterface) GetTodayData(ctx context.Context) ([]otrs.DayStatisticRow, error) {
+	dayStart := truncateToDay(time.Now())
+	articleList, err := gi.getArticles(ctx, dayStart, dayStart.Add(time.Hour*24))
+	if err != nil {
+		return nil, err
+	}
+
+	// Collect locked tickets of configured users.
+	ownerIDList := make([]int, 0, len(gi.Users))
+	for id := range gi.Users {
+		ownerIDList = append(ownerIDList, id)
+	}
+	ticketIDList, err := gi.searchTickets(ctx, map[string]interface{}{
+		"Locks":    lockedLockList,
+		"OwnerIDs": ownerIDList,
+	})
+	if err != nil {
+		return nil, err
+	}
+	lockedTicketList, err := gi.getTickets(ctx, ticketIDList, false)
+	if err != nil {
+		return nil, err
+	}
+
+	rowIndex := make(map[int]int, len(gi.Users))
+	otrsData := gi.emptyRows(rowIndex)
+
+	// Aggregate accounted time.
+	for _, a := range articleList {
+		otrsData[rowIndex[int(a.CreateBy)]].WorkTime += int(a.TimeUnit)
+	}
+
+	// Aggregate ticket statistic.
+	for _, t := range lockedTicketList {
+		i, ok := rowIndex[int(t.OwnerID)]
+		if !ok || !isLocked(t.Lock) {
+			continue
+		}
+		otrsData[i].LockedTicketCount++
+		if !gi.States.IsClosed(t.StateType) {
+			otrsData[i].NotClosedTicketCount++
+		}
+		if gi.States.IsOpen(t.StateType) {
+			otrsData[i].OpenTicketCount++
+		}
+	}
+
+	return otrsData, nil
+}
+
+// Get accounted work time and overtime for specified day.
+func (gi GenericInterface) GetCustomDayData(ctx context.Context, day time.Time) ([]otrs.DayStatisticRow, error) {
+	dayStart := truncateToDay(day)
+	articleList, err := gi.getArticles(ctx, dayStart, dayStart.Add(time.Hour*24))
+	if err != nil {
+		return nil, err
+	}
+
+	rowIndex := make(map[int]int, len(gi.Users))
+	otrsData := gi.emptyRows(rowIndex)
+
+	// Aggregate data based on the presence of the overtime mark.
+	for _, a := range articleList {
+		i := rowIndex[int(a.CreateBy)]
+		if gi.isOvertime(a) {
+			otrsData[i].OverTime += int(a.TimeUnit)
+		} else {
+			otrsData[i].WorkTime += int(a.TimeUnit)
+		}
+	}
+
+	return otrsData, nil
+}
+
+// Get accounted work time and overtime per user for every day in [from, to) period.
+func (gi GenericInterface) GetRangeData(ctx context.Context, from, to time.Time) ([]otrs.RangeStatisticRow, error) {
+	articleList, err := gi.getArticles(ctx, truncateToDay(from), truncateToDay(to))
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		day   time.Time
+		login string
+	}
+	rowIndex := make(map[key]int)
+	otrsData := make([]otrs.RangeStatisticRow, 0, 64)
+	for _, a := range articleList {
+		k := key{day: truncateToDay(a.createTime), login: gi.Users[int(a.CreateBy)]}
+		i, ok := rowIndex[k]
+		if !ok {
+			i = len(otrsData)
+			rowIndex[k] = i
+			otrsData = append(otrsData, otrs.RangeStatisticRow{Day: k.day, Login: k.login})
+		}
+		if gi.isOvertime(a) {
+			otrsData[i].OverTime += int(a.TimeUnit)
+		} else {
+			otrsData[i].WorkTime += int(a.TimeUnit)
+		}
+	}
+
+	return otrsData, nil
+}
+
+// Get accounted time entries of one user for specified day.
+func (gi GenericInterface) GetAccountedTimeDetails(ctx context.Context, login string, day time.Time) ([]otrs.AccountedTimeEntry, error) {
+	dayStart := truncateToDay(day)
+	articleList, err := gi.getArticles(ctx, dayStart, dayStart.Add(time.Hour*24))
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(articleList, func(i, j int) bool { return articleList[i].createTime.Before(articleList[j].createTime) })
+
+	entryList := make([]otrs.AccountedTimeEntry, 0, 32)
+	for _, a := range articleList {
+		if gi.Users[int(a.CreateBy)] != login {
+			continue
+		}
+		entryList = append(entryList, otrs.AccountedTimeEntry{
+			TicketNumber: a.ticket.TicketNumber,
+			Title:        a.ticket.Title,
+			Queue:        a.ticket.Queue,
+			ArticleID:    int(a.ArticleID),
+			Minutes:      int(a.TimeUnit),
+			IsOverTime:   gi.isOvertime(a),
+		})
+	}
+
+	return entryList, nil
+}
+
+// Get accounted time per user and category for every day in [from, to) period.
+// Customer company is identified by ticket CustomerID.
+func (gi GenericInterface) GetCategoryData(ctx context.Context, from, to time.Time) ([]otrs.CategoryStatisticRow, error) {
+	articleList, err := gi.getArticles(ctx, truncateToDay(from), truncateToDay(to))
+	if err != nil {
+		return nil, err
+	}
+
+	groupList := make([]otrs.TicketGroupRow, 0, len(articleList))
+	for _, a := range articleList {
+		group := otrs.TicketGroupRow{
+			Day:      truncateToDay(a.createTime),
+			Login:    gi.Users[int(a.CreateBy)],
+			Queue:    a.ticket.Queue,
+			Service:  a.ticket.Service,
+			Customer: a.ticket.CustomerID,
+		}
+		if gi.isOvertime(a) {
+			group.OverTime = int(a.TimeUnit)
+		} else {
+			group.WorkTime = int(a.TimeUnit)
+		}
+		groupList = append(groupList, group)
+	}
+
+	return otrs.AggregateByCategory(groupList), nil
+}
+
+// Close web service session.
+// Session expires on OTRS side, so nothing to do.
+func (gi GenericInterface) Stop() error {
+	return nil
+}
+
+// Return rows for all configured users ordered by login and fill map from user ID to row index.
+// Web service doesn't provide user last name, so LastName is empty.
+func (gi GenericInterface) emptyRows(rowIndex map[int]int) []otrs.DayStatisticRow {
+	idList := make([]int, 0, len(gi.Users))
+	for id := range gi.Users {
+		idList = append(idList, id)
+	}
+	sort.Slice(idList, func(i, j int) bool { return gi.Users[idList[i]] < gi.Users[idList[j]] })
+
+	otrsData := make([]otrs.DayStatisticRow, 0, len(idList))
+	for _, id := range idList {
+		rowIndex[id] = len(otrsData)
+		otrsData = append(otrsData, otrs.DayStatisticRow{Login: gi.Users[id]})
+	}
+	return otrsData
+}
+
+// Check if article marked as overtime.
+func (gi GenericInterface) isOvertime(a article) bool {
+	for _, df := range a.DynamicField {
+		if df.Name == gi.OvertimeField {
+			return isOvertimeValue(df.Value)
+		}
+	}
+	return false
+}
+
+// Check if dynamic field value marks overtime.
+// Checkbox value is returned as number or string, values of multiselect field as list.
+// Empty and "0" values don't mark overtime.
+func isOvertimeValue(raw json.RawMessage) bool {
+	var value string
+	var valueList []string
+	var number float64
+	switch {
+	case json.Unmarshal(raw, &value) == nil:
+		valueList = []string{value}
+	case json.Unmarshal(raw, &valueList) == nil:
+	case json.Unmarshal(raw, &number) == nil:
+		return number != 0
+	default:
+		return false
+	}
+
+	for _, value = range valueList {
+		if value != "" && value != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+// Check if ticket lock type means locked ticket.
+func isLocked(lock string) bool {
+	for _, locked := range lockedLockList {
+		if lock == locked {
+			return true
+		}
+	}
+	return false
+}
+
+// Return articles with accounted time created by configured users in [from, to) period.
+func (gi GenericInterface) getArticles(ctx context.Context, from, to time.Time) ([]article, error) {
+	ticketIDList, err := gi.searchTicketsByArticleTime(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	ticketList, err := gi.getTickets(ctx, ticketIDList, true)
+	if err != nil {
+		return nil, err
+	}
+
+	articleList := make([]article, 0, 64)
+	for _, t := range ticketList {
+		for _, a := range t.Article {
+			if _, ok := gi.Users[int(a.CreateBy)]; !ok || a.TimeUnit == 0 {
+				continue
+			}
+			createTime, err := time.ParseInLocation(dateTimeFormatLayout, a.CreateTime, from.Location())
+			if err != nil {
+				return nil, err
+			}
+			if createTime.Before(from) || !createTime.Before(to) {
+				continue
+			}
+			a.ticket = t
+			a.ticket.Article = nil
+			a.createTime = createTime
+			articleList = append(articleList, a)
+		}
+	}
+	return articleList, nil
+}
+
+// Search tickets with articles created in [from, to) period and return ticket ID list.
+// If search result reaches the limit, period is split in halves, so result is never truncated.
+func (gi GenericInterface) searchTicketsByArticleTime(ctx context.Context, from, to time.Time) ([]string, error) {
+	ticketIDList, err := gi.searchTickets(ctx, map[string]interface{}{
+		"ArticleCreateTimeNewerDate": from.Format(dateTimeFormatLayout),
+		"ArticleCreateTimeOlderDate": to.Add(-time.Second).Format(dateTimeFormatLayout),
+	})
+	if !errors.Is(err, errSearchLimit) {
+		return ticketIDList, err
+	}
+	if to.Sub(from) <= time.Second {
+		return nil, fmt.Errorf("%w: articles created at '%s'", err, from.Format(dateTimeFormatLayout))
+	}
+
+	// Ticket with articles in both halves is found twice.
+	middle := from.Add(to.Sub(from) / 2).Truncate(time.Second)
+	firstList, err := gi.searchTicketsByArticleTime(ctx, from, middle)
+	if err != nil {
+		return nil, err
+	}
+	secondList, err := gi.searchTicketsByArticleTime(ctx, middle, to)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool, len(firstList))
+	for _, id := range firstList {
+		found[id] = true
+	}
+	for _, id := range secondList {
+		if !found[id] {
+			firstList = append(firstList, id)
+		}
+	}
+	return firstList, nil
+}
+
+// Search tickets by provided criteria and return ticket ID list.
+// If result reaches the search limit, return errSearchLimit, because result may be truncated.
+func (gi GenericInterface) searchTickets(ctx context.Context, criteria map[string]interface{}) ([]string, error) {
+	limit := gi.searchLimit
+	if limit == 0 {
+		limit = ticketSearchLimit
+	}
+	criteria["Limit"] = limit
+
+	var response struct {
+		TicketID []string
+	}
+	err := gi.call(ctx, ticketSearchRoute, criteria, &response)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.TicketID) >= limit {
+		return nil, fmt.Errorf("%w '%v'", errSearchLimit, limit)
+	}
+	return response.TicketID, nil
+}
+
+// Get tickets data by ID list. Split request into batches.
+func (gi GenericInterface) getTickets(ctx context.Context, ticketIDList []string, withArticles bool) ([]ticket, error) {
+	ticketList := make([]ticket, 0, len(ticketIDList))
+	for start := 0; start < len(ticketIDList); start += ticketGetBatchSize {
+		end := start + ticketGetBatchSize
+		if end > len(ticketIDList) {
+			end = len(ticketIDList)
+		}
+
+		var response struct {
+			Ticket []ticket
+		}
+		request := map[string]interface{}{
+			"TicketID": strings.Join(ticketIDList[start:end], ","),
+		}
+		if withArticles {
+			request["AllArticles"] = 1
+			request["DynamicFields"] = 1
+		}
+		err := gi.call(ctx, ticketGetRoute, request, &response)
+		if err != nil {
+			return nil, err
+		}
+		ticketList = append(ticketList, response.Ticket...)
+	}
+	return ticketList, nil
+}
+
+// Call web service operation with current session.
+// If session is expired, create new one and repeat call once.
+func (gi GenericInterface) call(ctx context.Context, route string, request map[string]interface{}, response interface{}) error {
+	for attempt := 0; attempt < 2; attempt++ {
+		id, err := gi.sessionID(ctx, attempt > 0)
+		if err != nil {
+			return err
+		}
+		request["SessionID"] = id
+
+		body, err := gi.post(ctx, route, request)
+		if err != nil {
+			return err
+		}
+
+		serviceErr, err := decodeError(body)
+		if err != nil {
+			return err
+		}
+		if serviceErr == nil {
+			return json.Unmarshal(body, response)
+		}
+		if !strings.HasSuffix(serviceErr.ErrorCode, ".AuthFail") {
+			return fmt.Errorf("web service error '%s': '%s'", serviceErr.ErrorCode, serviceErr.ErrorMessage)
+		}
+	}
+	return errors.New("web service authorisation failed")
+}
+
+// Return current session ID. Create new session if it doesn't exist or renew required.
+func (gi GenericInterface) sessionID(ctx context.Context, renew bool) (string, error) {
+	gi.session.mx.Lock()
+	defer gi.session.mx.Unlock()
+
+	if gi.session.id != "" && !renew {
+		return gi.session.id, nil
+	}
+
+	body, err := gi.post(ctx, sessionCreateRoute, map[string]interface{}{
+		"UserLogin": gi.UserLogin,
+		"Password":  gi.Password,
+	})
+	if err != nil {
+		return "", err
+	}
+	serviceErr, err := decodeError(body)
+	if err != nil {
+		return "", err
+	}
+	if serviceErr != nil {
+		return "", fmt.Errorf("can't create web service session '%s': '%s'", serviceErr.ErrorCode, serviceErr.ErrorMessage)
+	}
+
+	var response struct {
+		SessionID string
+	}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return "", err
+	}
+
+	gi.session.id = response.SessionID
+	return gi.session.id, nil
+}
+
+// Send JSON request to web service and return response body.
+func (gi GenericInterface) post(ctx context.Context, route string, request interface{}) ([]byte, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, gi.URL+route, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := gi.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("web service '%s' returned status '%s'", route, resp.Status)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Extract error from web service response. Return nil if response doesn't contain error.
+func decodeError(body []byte) (*serviceError, error) {
+	var response struct {
+		Error *serviceError
+	}
+	err := json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, err
+	}
+	return response.Error, nil
+}
+
+// Return start of the day in the same location.
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
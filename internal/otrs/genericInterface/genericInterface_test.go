@@ -0,0 +1,375 @@
+package genericInterface
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testWebService = "TimeAccounting"
+
+// Fake OTRS web service.
+type fakeService struct {
+	mx           sync.Mutex
+	sessionCount int             // Number of created sessions.
+	expired      map[string]bool // Sessions rejected with AuthFail.
+	ticketList   []ticket
+	batchList    []int // Number of tickets requested by every TicketGet call.
+	loginList    []string
+}
+
+func newFakeService(t *testing.T) (*fakeService, *httptest.Server) {
+	fs := &fakeService{expired: make(map[string]bool)}
+	prefix := "/otrs/nph-genericinterface.pl/Webservice/" + testWebService
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !strings.HasPrefix(r.URL.Path, prefix) {
+			http.NotFound(w, r)
+			return
+		}
+		request := make(map[string]interface{})
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			t.Errorf("invalid request body: %v", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		response := fs.handle(strings.TrimPrefix(r.URL.Path, prefix), request)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return fs, server
+}
+
+func (fs *fakeService) handle(route string, request map[string]interface{}) interface{} {
+	fs.mx.Lock()
+	defer fs.mx.Unlock()
+
+	if route == sessionCreateRoute {
+		fs.loginList = append(fs.loginList, fmt.Sprint(request["UserLogin"]))
+		if request["Password"] != "secret" {
+			return map[string]interface{}{"Error": serviceError{ErrorCode: "SessionCreate.AuthFail", ErrorMessage: "wrong password"}}
+		}
+		fs.sessionCount++
+		return map[string]string{"SessionID": fmt.Sprint("session-", fs.sessionCount)}
+	}
+
+	sessionID, _ := request["SessionID"].(string)
+	if sessionID == "" || fs.expired[sessionID] {
+		return map[string]interface{}{"Error": serviceError{ErrorCode: strings.TrimPrefix(route, "/") + ".AuthFail", ErrorMessage: "session expired"}}
+	}
+
+	switch route {
+	case ticketSearchRoute:
+		idList := make([]string, 0, len(fs.ticketList))
+		for _, t := range fs.ticketList {
+			if matchSearch(t, request) {
+				idList = append(idList, fmt.Sprint(t.TicketID))
+			}
+		}
+		if limit, ok := request["Limit"].(float64); ok && len(idList) > int(limit) {
+			idList = idList[:int(limit)]
+		}
+		return map[string][]string{"TicketID": idList}
+	case ticketGetRoute:
+		idList := strings.Split(fmt.Sprint(request["TicketID"]), ",")
+		fs.batchList = append(fs.batchList, len(idList))
+		requested := make(map[string]bool, len(idList))
+		for _, id := range idList {
+			requested[id] = true
+		}
+		ticketList := make([]ticket, 0, len(idList))
+		for _, t := range fs.ticketList {
+			if requested[fmt.Sprint(t.TicketID)] {
+				ticketList = append(ticketList, t)
+			}
+		}
+		return map[string][]ticket{"Ticket": ticketList}
+	}
+	return map[string]interface{}{"Error": serviceError{ErrorCode: "Unknown", ErrorMessage: route}}
+}
+
+// Check if ticket matches TicketSearch criteria supported by fake service.
+func matchSearch(t ticket, request map[string]interface{}) bool {
+	if newer, ok := request["ArticleCreateTimeNewerDate"].(string); ok {
+		older, _ := request["ArticleCreateTimeOlderDate"].(string)
+		found := false
+		for _, a := range t.Article {
+			found = found || a.CreateTime >= newer && a.CreateTime <= older
+		}
+		if !found {
+			return false
+		}
+	}
+	if lockList, ok := request["Locks"].([]interface{}); ok && !containsValue(lockList, t.Lock) {
+		return false
+	}
+	if ownerIDList, ok := request["OwnerIDs"].([]interface{}); ok && !containsValue(ownerIDList, float64(t.OwnerID)) {
+		return false
+	}
+	return true
+}
+
+// Check if decoded JSON list contains value.
+func containsValue(list []interface{}, value interface{}) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Return number of created sessions and logins used for creation.
+func (fs *fakeService) sessions() (int, []string) {
+	fs.mx.Lock()
+	defer fs.mx.Unlock()
+	return fs.sessionCount, append([]string(nil), fs.loginList...)
+}
+
+func newTestProvider(t *testing.T, url string) GenericInterface {
+	t.Helper()
+	gi, err := NewProvider(context.Background(), url+"/otrs/", testWebService, "agent", "secret", "Overtime",
+		map[int]string{1: "obrien", 2: "smith"}, otrs.DefaultStateClassification())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gi
+}
+
+// Return article of user with accounted time created at specified time.
+func testArticle(id, userID, minutes int, createTime time.Time, overtime string) article {
+	a := article{
+		ArticleID:  flexNumber(id),
+		CreateBy:   flexNumber(userID),
+		CreateTime: createTime.Format(dateTimeFormatLayout),
+		TimeUnit:   flexNumber(minutes),
+	}
+	if overtime != "" {
+		a.DynamicField = []dynamicField{{Name: "Overtime", Value: json.RawMessage(overtime)}}
+	}
+	return a
+}
+
+func TestNewProviderCreatesSession(t *testing.T) {
+	fs, server := newFakeService(t)
+	gi := newTestProvider(t, server.URL)
+
+	sessionCount, loginList := fs.sessions()
+	if sessionCount != 1 || len(loginList) != 1 || loginList[0] != "agent" {
+		t.Fatalf("expected one session of 'agent', got %v sessions of %v", sessionCount, loginList)
+	}
+	if gi.session.id != "session-1" {
+		t.Errorf("unexpected session ID '%s'", gi.session.id)
+	}
+
+	// Provider is usable after failed session creation and creates session on next call.
+	unavailable, err := NewProvider(context.Background(), server.URL+"/otrs", testWebService, "agent", "wrong", "Overtime",
+		map[int]string{1: "obrien"}, otrs.DefaultStateClassification())
+	if !errors.Is(err, otrs.ErrUnavailable) || !strings.Contains(err.Error(), "SessionCreate.AuthFail") {
+		t.Fatalf("expected session creation error, got %v", err)
+	}
+	unavailable.Password = "secret"
+	_, err = unavailable.GetCustomDayData(context.Background(), time.Now())
+	if err != nil {
+		t.Errorf("provider must create session on next call, got %v", err)
+	}
+}
+
+func TestCallRenewsSessionAfterAuthFail(t *testing.T) {
+	fs, server := newFakeService(t)
+	gi := newTestProvider(t, server.URL)
+
+	fs.mx.Lock()
+	fs.expired["session-1"] = true
+	fs.mx.Unlock()
+
+	_, err := gi.GetCustomDayData(context.Background(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionCount, _ := fs.sessions()
+	if sessionCount != 2 || gi.session.id != "session-2" {
+		t.Errorf("expected renewed session 'session-2', got '%s' after %v sessions", gi.session.id, sessionCount)
+	}
+
+	// Renewed session is rejected too, error is returned instead of endless renewal.
+	fs.mx.Lock()
+	fs.expired["session-2"] = true
+	fs.expired["session-3"] = true
+	fs.mx.Unlock()
+	_, err = gi.GetCustomDayData(context.Background(), time.Now())
+	if err == nil {
+		t.Error("expected authorisation error")
+	}
+}
+
+func TestGetCustomDayData(t *testing.T) {
+	fs, server := newFakeService(t)
+	gi := newTestProvider(t, server.URL)
+
+	day := time.Date(2021, 3, 15, 0, 0, 0, 0, time.Local)
+	// 120 tickets require 3 TicketGet batches.
+	for id := 1; id <= 120; id++ {
+		t := ticket{TicketID: flexNumber(id), TicketNumber: fmt.Sprint(202103150000 + id), Queue: "Support"}
+		t.Article = []article{testArticle(id*10, 1, 10, day.Add(time.Hour*10), "")}
+		switch id {
+		case 1:
+			// Overtime mark as number, string and list.
+			t.Article = append(t.Article,
+				testArticle(id*10+1, 1, 30, day.Add(time.Hour*20), "1"),
+				testArticle(id*10+2, 2, 15, day.Add(time.Hour*21), `"1"`),
+				testArticle(id*10+3, 2, 5, day.Add(time.Hour*22), `["1"]`),
+				// Empty overtime mark is work time.
+				testArticle(id*10+4, 2, 7, day.Add(time.Hour*22), `""`),
+			)
+		case 2:
+			t.Article = append(t.Article,
+				// Unknown user, other days and articles without time are ignored.
+				testArticle(id*10+1, 99, 60, day.Add(time.Hour), ""),
+				testArticle(id*10+2, 2, 60, day.Add(-time.Second), ""),
+				testArticle(id*10+3, 2, 60, day.Add(time.Hour*24), ""),
+				testArticle(id*10+4, 2, 0, day.Add(time.Hour), ""),
+			)
+		}
+		fs.ticketList = append(fs.ticketList, t)
+	}
+
+	rowList, err := gi.GetCustomDayData(context.Background(), day.Add(time.Hour*12))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprint(fs.batchList) != "[50 50 20]" {
+		t.Errorf("unexpected TicketGet batches %v", fs.batchList)
+	}
+	expected := []otrs.DayStatisticRow{
+		{Login: "obrien", WorkTime: 1200, OverTime: 30},
+		{Login: "smith", WorkTime: 7, OverTime: 20},
+	}
+	if fmt.Sprint(rowList) != fmt.Sprint(expected) {
+		t.Errorf("unexpected day data %+v, expected %+v", rowList, expected)
+	}
+}
+
+func TestGetAccountedTimeDetails(t *testing.T) {
+	fs, server := newFakeService(t)
+	gi := newTestProvider(t, server.URL)
+
+	day := time.Date(2021, 3, 15, 0, 0, 0, 0, time.Local)
+	fs.ticketList = []ticket{{
+		TicketID:     7,
+		TicketNumber: "2021031510000071",
+		Title:        "Printer",
+		Queue:        "Support",
+		Article: []article{
+			testArticle(72, 1, 20, day.Add(time.Hour*19), "1"),
+			testArticle(71, 1, 15, day.Add(time.Hour*9), ""),
+			testArticle(73, 2, 45, day.Add(time.Hour*11), ""),
+		},
+	}}
+
+	entryList, err := gi.GetAccountedTimeDetails(context.Background(), "obrien", day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []otrs.AccountedTimeEntry{
+		{TicketNumber: "2021031510000071", Title: "Printer", Queue: "Support", ArticleID: 71, Minutes: 15},
+		{TicketNumber: "2021031510000071", Title: "Printer", Queue: "Support", ArticleID: 72, Minutes: 20, IsOverTime: true},
+	}
+	if fmt.Sprint(entryList) != fmt.Sprint(expected) {
+		t.Errorf("unexpected details %+v, expected %+v", entryList, expected)
+	}
+}
+
+func TestGetTodayData(t *testing.T) {
+	fs, server := newFakeService(t)
+	gi := newTestProvider(t, server.URL)
+
+	dayStart := truncateToDay(time.Now())
+	fs.ticketList = []ticket{
+		{TicketID: 1, Lock: "lock", StateType: otrs.StateTypeOpen, OwnerID: 1,
+			Article: []article{testArticle(11, 1, 30, dayStart.Add(time.Minute), "1")}},
+		{TicketID: 2, Lock: "tmp_lock", StateType: otrs.StateTypeClosed, OwnerID: 1},
+		{TicketID: 3, Lock: "lock", StateType: "new", OwnerID: 2},
+		// Unlocked tickets and tickets of unknown users are not counted.
+		{TicketID: 4, Lock: "unlock", StateType: otrs.StateTypeOpen, OwnerID: 2},
+		{TicketID: 5, Lock: "lock", StateType: otrs.StateTypeOpen, OwnerID: 99},
+	}
+
+	rowList, err := gi.GetTodayData(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []otrs.DayStatisticRow{
+		{Login: "obrien", WorkTime: 30, NotClosedTicketCount: 1, LockedTicketCount: 2, OpenTicketCount: 1},
+		{Login: "smith", NotClosedTicketCount: 1, LockedTicketCount: 1},
+	}
+	if fmt.Sprint(rowList) != fmt.Sprint(expected) {
+		t.Errorf("unexpected today data %+v, expected %+v", rowList, expected)
+	}
+}
+
+func TestSearchLimitSplitsPeriod(t *testing.T) {
+	fs, server := newFakeService(t)
+	gi := newTestProvider(t, server.URL)
+	gi.searchLimit = 2
+
+	day := time.Date(2021, 3, 15, 0, 0, 0, 0, time.Local)
+	for id := 1; id <= 5; id++ {
+		fs.ticketList = append(fs.ticketList, ticket{TicketID: flexNumber(id),
+			Article: []article{testArticle(id*10, 1, id, day.Add(time.Hour*time.Duration(id*4)), "")}})
+	}
+	// Ticket with articles in several parts of period is counted once.
+	fs.ticketList[0].Article = append(fs.ticketList[0].Article, testArticle(11, 2, 100, day.Add(time.Hour*23), ""))
+
+	rowList, err := gi.GetCustomDayData(context.Background(), day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []otrs.DayStatisticRow{{Login: "obrien", WorkTime: 15}, {Login: "smith", WorkTime: 100}}
+	if fmt.Sprint(rowList) != fmt.Sprint(expected) {
+		t.Errorf("unexpected day data %+v, expected %+v", rowList, expected)
+	}
+
+	// Period can't be split further than one second.
+	for id := 6; id <= 7; id++ {
+		fs.mx.Lock()
+		fs.ticketList = append(fs.ticketList, ticket{TicketID: flexNumber(id),
+			Article: []article{testArticle(id*10, 1, 1, day.Add(time.Hour*4), "")}})
+		fs.mx.Unlock()
+	}
+	_, err = gi.GetCustomDayData(context.Background(), day)
+	if !errors.Is(err, errSearchLimit) {
+		t.Errorf("expected search limit error, got '%v'", err)
+	}
+}
+
+func TestIsOvertimeValue(t *testing.T) {
+	for value, expected := range map[string]bool{
+		`1`:         true,
+		`"1"`:       true,
+		`["1"]`:     true,
+		`["0","1"]`: true,
+		`0`:         false,
+		`"0"`:       false,
+		`""`:        false,
+		`["0"]`:     false,
+		`[""]`:      false,
+		`[]`:        false,
+		`null`:      false,
+		`{"a":"1"}`: false,
+	} {
+		if isOvertimeValue(json.RawMessage(value)) != expected {
+			t.Errorf("isOvertimeValue(%s) must be %v", value, expected)
+		}
+	}
+}
@@ -0,0 +1,449 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs/sqlConnection"
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"strings"
+	"time"
+)
+
+const (
+	dayFormatLayout = "2006-01-02" // Day format layout used in queries.
+
+	// Get ID and object type of dynamic field by name.
+	getDynamicFieldQuery = `
+select id, object_type
+	from dynamic_field
+	where name = ?
+;
+`
+
+	// Get accounted time and ticket statistic.
+	getTodayDataQuery = `
+select
+	users.login,
+	users.last_name,
+	cast(coalesce(ta.time, 0) as signed) as time,
+	cast(coalesce(t.not_closed, 0) as signed) as not_closed,
+	cast(coalesce(t.locked, 0) as signed) as locked,
+	cast(coalesce(t.open_count, 0) as signed) as open_count
+from
+	users
+	left join
+		(select create_by, sum(time_unit) as time
+			from time_accounting
+			where
+					create_time >= ?
+				and create_time <  ?
+			group by create_by
+		) as ta on ta.create_by = users.id
+	left join
+		(select
+			user_id,
+			count(*) as locked,
+			sum(tst.name not in (%s)) as not_closed,
+			sum(tst.name in (%s)) as open_count
+			from ticket
+				join ticket_state as ts on ts.id = ticket.ticket_state_id
+				join ticket_state_type as tst on tst.id = ts.type_id
+			where
+				ticket_lock_id != 1
+			group by user_id
+		) as t on t.user_id = users.id
+	where users.login in (%s)
+	order by users.login
+;
+`
+
+	// Get accounted work time and overtime.
+	// Time from articles with the overtime mark is summed separately.
+	getCustomDayDataQuery = `
+select
+	users.login,
+	users.last_name,
+	cast(coalesce(sum(case when coalesce(overtime.value_int, 0) = 0 then ta.time_unit end), 0) as signed) as work_time,
+	cast(coalesce(sum(case when coalesce(overtime.value_int, 0) != 0 then ta.time_unit end), 0) as signed) as over_time
+from
+	users
+	left join
+		time_accounting as ta on ta.create_by = users.id
+			and ta.create_time >= ?
+			and ta.create_time <  ?
+	left join
+		dynamic_field_value as overtime on overtime.object_id = ta.article_id
+			and overtime.field_id = ?
+	where users.login in (%s)
+	group by users.login, users.last_name
+	order by users.login
+;
+`
+
+	// Get accounted work time and overtime grouped by day and user.
+	getRangeDataQuery = `
+select
+	date_format(ta.create_time, '%%Y-%%m-%%d') as day,
+	users.login,
+	cast(coalesce(sum(case when coalesce(overtime.value_int, 0) = 0 then ta.time_unit end), 0) as signed) as work_time,
+	cast(coalesce(sum(case when coalesce(overtime.value_int, 0) != 0 then ta.time_unit end), 0) as signed) as over_time
+from
+	time_accounting as ta
+	join users on users.id = ta.create_by
+	left join dynamic_field_value as overtime on overtime.object_id = ta.article_id
+		and overtime.field_id = ?
+where
+		ta.create_time >= ?
+	and ta.create_time <  ?
+	and users.login in (%s)
+group by 1, 2
+order by 1, 2
+;
+`
+
+	// Get time accounting entries of one user.
+	getAccountedTimeDetailsQuery = `
+select
+	ticket.tn,
+	ticket.title,
+	queue.name,
+	coalesce(ta.article_id, 0),
+	cast(ta.time_unit as signed),
+	coalesce(overtime.value_int, 0) != 0
+from
+	time_accounting as ta
+	join users on users.id = ta.create_by
+	join ticket on ticket.id = ta.ticket_id
+	join queue on queue.id = ticket.queue_id
+	left join dynamic_field_value as overtime on overtime.object_id = ta.article_id
+		and overtime.field_id = ?
+where
+		ta.create_time >= ?
+	and ta.create_time <  ?
+	and users.login = ?
+order by ta.create_time
+;
+`
+
+	// Get accounted work time and overtime grouped by day, user, queue, service and customer company.
+	getCategoryDataQuery = `
+select
+	date_format(ta.create_time, '%%Y-%%m-%%d') as day,
+	users.login,
+	queue.name,
+	coalesce(service.name, ''),
+	coalesce(customer_company.name, ticket.customer_id, ''),
+	cast(coalesce(sum(case when coalesce(overtime.value_int, 0) = 0 then ta.time_unit end), 0) as signed) as work_time,
+	cast(coalesce(sum(case when coalesce(overtime.value_int, 0) != 0 then ta.time_unit end), 0) as signed) as over_time
+from
+	time_accounting as ta
+	join users on users.id = ta.create_by
+	join ticket on ticket.id = ta.ticket_id
+	join queue on queue.id = ticket.queue_id
+	left join service on service.id = ticket.service_id
+	left join customer_company on customer_company.customer_id = ticket.customer_id
+	left join dynamic_field_value as overtime on overtime.object_id = ta.article_id
+		and overtime.field_id = ?
+where
+		ta.create_time >= ?
+	and ta.create_time <  ?
+	and users.login in (%s)
+group by 1, 2, 3, 4, 5
+;
+`
+)
+
+// Implement otrs Provider.
+// Connection to MySQL or MariaDB DB that contains OTRS data is opened on first query
+// and reopened after connection loss, e.g. OTRS DB restart.
+type MySQL struct {
+	UserList []string
+	States   otrs.StateClassification
+	conn     *sqlConnection.Connection // Shared by copies of provider.
+}
+
+// MySQL server error number of server shutdown in progress.
+const serverShutdownErrorNumber = 1053
+
+// Initialise and return OTRS DB connector.
+func NewProvider(ctx context.Context, host, port, user, password, dbName, sslMode, overtimeField string, userList []string, states otrs.StateClassification) (MySQL, error) {
+	// Return error if empty slice provided.
+	if len(userList) < 1 {
+		return MySQL{}, errors.New("user list must contain at least one user")
+	}
+	err := states.Validate()
+	if err != nil {
+		return MySQL{}, err
+	}
+
+	// Construct DB connection string.
+	cfg := mysqlDriver.NewConfig()
+	cfg.Net = "tcp"
+	cfg.Addr = fmt.Sprintf("%s:%s", host, port)
+	cfg.User = user
+	cfg.Passwd = password
+	cfg.DBName = dbName
+	cfg.TLSConfig = tlsConfig(sslMode)
+
+	var m MySQL
+	m.UserList = userList
+	m.States = states
+	m.conn = sqlConnection.New("mysql", cfg.FormatDSN(), overtimeField, getDynamicFieldQuery, isConnectionError)
+
+	// Connect to DB. Provider is usable on connection error and connects again on next query.
+	_, _, err = m.conn.Get(ctx)
+	if err != nil {
+		return m, fmt.Errorf("%w: %v", otrs.ErrUnavailable, err)
+	}
+
+	return m, nil
+}
+
+// Check if MySQL error means lost connection to DB.
+func isConnectionError(err error) bool {
+	var mysqlErr *mysqlDriver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == serverShutdownErrorNumber
+	}
+	return errors.Is(err, mysqlDriver.ErrInvalidConn)
+}
+
+// Convert PostgreSQL like SSL mode from config into MySQL driver TLS option.
+func tlsConfig(sslMode string) string {
+	switch sslMode {
+	case "require":
+		return "skip-verify"
+	case "verify-ca", "verify-full":
+		return "true"
+	default:
+		return "false"
+	}
+}
+
+// Return placeholders for "in" list of values and values as query arguments.
+// MySQL "in" list can't be empty, so empty list is replaced by single NULL that matches nothing.
+func inList(values []string) (string, []interface{}) {
+	if len(values) == 0 {
+		return "null", nil
+	}
+	args := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		args = append(args, value)
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(values)), ","), args
+}
+
+// Get today data from OTRS BD.
+func (m MySQL) GetTodayData(ctx context.Context) ([]otrs.DayStatisticRow, error) {
+	db, _, err := m.conn.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Assemble Query string.
+	closedPlaceholders, closedArgs := inList(m.States.Closed)
+	openPlaceholders, openArgs := inList(m.States.Open)
+	userPlaceholders, userArgs := inList(m.UserList)
+	query := fmt.Sprintf(getTodayDataQuery, closedPlaceholders, openPlaceholders, userPlaceholders)
+	today := truncateToDay(time.Now())
+	args := []interface{}{today.Format(dayFormatLayout), today.AddDate(0, 0, 1).Format(dayFormatLayout)}
+	args = append(append(append(args, closedArgs...), openArgs...), userArgs...)
+
+	// Query for data.
+	rowList, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, m.conn.Check(err)
+	}
+	defer rowList.Close()
+
+	// Collect data from from query result.
+	var login, lastName string
+	var timeAccounted, notClosedTicketCount, lockedTicketCount, openTicketCount int
+	var otrsData = make([]otrs.DayStatisticRow, 0, 32)
+	for rowList.Next() {
+		err = rowList.Scan(&login, &lastName, &timeAccounted, &notClosedTicketCount, &lockedTicketCount, &openTicketCount)
+		if err != nil {
+			return nil, err
+		}
+		// Append current row.
+		otrsData = append(otrsData,
+			otrs.DayStatisticRow{
+				Login:                login,
+				LastName:             lastName,
+				WorkTime:             timeAccounted,
+				NotClosedTicketCount: notClosedTicketCount,
+				LockedTicketCount:    lockedTicketCount,
+				OpenTicketCount:      openTicketCount,
+			})
+	}
+
+	return otrsData, m.conn.Check(rowList.Err())
+}
+
+// Get accounted work time and overtime for specified day.
+func (m MySQL) GetCustomDayData(ctx context.Context, day time.Time) ([]otrs.DayStatisticRow, error) {
+	db, fieldID, err := m.conn.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Assemble Query string.
+	userPlaceholders, userArgs := inList(m.UserList)
+	query := fmt.Sprintf(getCustomDayDataQuery, userPlaceholders)
+	dayStart := truncateToDay(day)
+	args := []interface{}{dayStart.Format(dayFormatLayout), dayStart.AddDate(0, 0, 1).Format(dayFormatLayout), fieldID}
+	args = append(args, userArgs...)
+
+	// Query for data.
+	rowList, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, m.conn.Check(err)
+	}
+	defer rowList.Close()
+
+	// Collect data from from query result.
+	var login, lastName string
+	var workTime, overTime int
+	var otrsData = make([]otrs.DayStatisticRow, 0, 32)
+	for rowList.Next() {
+		err = rowList.Scan(&login, &lastName, &workTime, &overTime)
+		if err != nil {
+			return nil, err
+		}
+		// Append current row.
+		otrsData = append(otrsData,
+			otrs.DayStatisticRow{
+				Login:    login,
+				LastName: lastName,
+				WorkTime: workTime,
+				OverTime: overTime,
+			})
+	}
+
+	return otrsData, m.conn.Check(rowList.Err())
+}
+
+// Get accounted work time and overtime per user for every day in [from, to) period.
+func (m MySQL) GetRangeData(ctx context.Context, from, to time.Time) ([]otrs.RangeStatisticRow, error) {
+	db, fieldID, err := m.conn.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Assemble Query string.
+	userPlaceholders, userArgs := inList(m.UserList)
+	query := fmt.Sprintf(getRangeDataQuery, userPlaceholders)
+	args := []interface{}{fieldID, from.Format(dayFormatLayout), to.Format(dayFormatLayout)}
+	args = append(args, userArgs...)
+
+	// Query for data.
+	rowList, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, m.conn.Check(err)
+	}
+	defer rowList.Close()
+
+	// Collect data from from query result.
+	var day string
+	var otrsData = make([]otrs.RangeStatisticRow, 0, 64)
+	for rowList.Next() {
+		var row otrs.RangeStatisticRow
+		err = rowList.Scan(&day, &row.Login, &row.WorkTime, &row.OverTime)
+		if err != nil {
+			return nil, err
+		}
+		row.Day, err = time.ParseInLocation(dayFormatLayout, day, from.Location())
+		if err != nil {
+			return nil, err
+		}
+		otrsData = append(otrsData, row)
+	}
+
+	return otrsData, m.conn.Check(rowList.Err())
+}
+
+// Get accounted time entries of one user for specified day.
+func (m MySQL) GetAccountedTimeDetails(ctx context.Context, login string, day time.Time) ([]otrs.AccountedTimeEntry, error) {
+	// Query for data.
+	dayStart := truncateToDay(day)
+	db, fieldID, err := m.conn.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rowList, err := db.QueryContext(ctx, getAccountedTimeDetailsQuery,
+		fieldID, dayStart.Format(dayFormatLayout), dayStart.AddDate(0, 0, 1).Format(dayFormatLayout), login)
+	if err != nil {
+		return nil, m.conn.Check(err)
+	}
+	defer rowList.Close()
+
+	// Collect data from from query result.
+	var entryList = make([]otrs.AccountedTimeEntry, 0, 32)
+	for rowList.Next() {
+		var entry otrs.AccountedTimeEntry
+		err = rowList.Scan(&entry.TicketNumber, &entry.Title, &entry.Queue, &entry.ArticleID, &entry.Minutes, &entry.IsOverTime)
+		if err != nil {
+			return nil, err
+		}
+		entryList = append(entryList, entry)
+	}
+
+	return entryList, m.conn.Check(rowList.Err())
+}
+
+// Get accounted time per user and category for every day in [from, to) period.
+func (m MySQL) GetCategoryData(ctx context.Context, from, to time.Time) ([]otrs.CategoryStatisticRow, error) {
+	db, fieldID, err := m.conn.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Assemble Query string.
+	userPlaceholders, userArgs := inList(m.UserList)
+	query := fmt.Sprintf(getCategoryDataQuery, userPlaceholders)
+	args := []interface{}{fieldID, from.Format(dayFormatLayout), to.Format(dayFormatLayout)}
+	args = append(args, userArgs...)
+
+	// Query for data.
+	rowList, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, m.conn.Check(err)
+	}
+	defer rowList.Close()
+
+	// Collect data from from query result.
+	var day string
+	var groupList = make([]otrs.TicketGroupRow, 0, 64)
+	for rowList.Next() {
+		var group otrs.TicketGroupRow
+		err = rowList.Scan(&day, &group.Login, &group.Queue, &group.Service, &group.Customer, &group.WorkTime, &group.OverTime)
+		if err != nil {
+			return nil, err
+		}
+		group.Day, err = time.ParseInLocation(dayFormatLayout, day, from.Location())
+		if err != nil {
+			return nil, err
+		}
+		groupList = append(groupList, group)
+	}
+	if err = rowList.Err(); err != nil {
+		return nil, m.conn.Check(err)
+	}
+
+	return otrs.AggregateByCategory(groupList), nil
+}
+
+// Return start of the day in the same location.
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Close DB connection.
+func (m MySQL) Stop() error {
+	if m.conn == nil {
+		return nil
+	}
+	return m.conn.Close()
+}
@@ -0,0 +1,144 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs/sqlConnection/sqlConnectionTest"
+	"io"
+	"testing"
+	"time"
+)
+
+// Return provider connected to mock DB.
+func newMockProvider(t *testing.T, userList []string) (MySQL, sqlmock.Sqlmock) {
+	conn, mock := sqlConnectionTest.NewMock(t, isConnectionError)
+	return MySQL{UserList: userList, States: otrs.DefaultStateClassification(), conn: conn}, mock
+}
+
+func TestInList(t *testing.T) {
+	for _, c := range []struct {
+		values       []string
+		placeholders string
+		args         string
+	}{
+		{values: nil, placeholders: "null", args: "[]"},
+		{values: []string{"O'Brien"}, placeholders: "?", args: "[O'Brien]"},
+		{values: []string{"O'Brien", "smith", `back\slash`}, placeholders: "?,?,?", args: `[O'Brien smith back\slash]`},
+	} {
+		placeholders, args := inList(c.values)
+		if placeholders != c.placeholders || fmt.Sprint(args) != c.args {
+			t.Errorf("inList(%q) = '%s', %v; expected '%s', %s", c.values, placeholders, args, c.placeholders, c.args)
+		}
+	}
+}
+
+func TestGetTodayData(t *testing.T) {
+	m, mock := newMockProvider(t, []string{"O'Brien", "smith"})
+
+	mock.ExpectQuery(fmt.Sprintf(getTodayDataQuery, "?,?", "?", "?,?")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "closed", "merged", "open", "O'Brien", "smith").
+		WillReturnRows(sqlmock.NewRows([]string{"login", "last_name", "time", "not_closed", "locked", "open_count"}).
+			AddRow("O'Brien", "O'Brien", 90, 2, 3, 1).
+			AddRow("smith", "Smith", 0, 0, 0, 0))
+
+	rowList, err := m.GetTodayData(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []otrs.DayStatisticRow{
+		{Login: "O'Brien", LastName: "O'Brien", WorkTime: 90, NotClosedTicketCount: 2, LockedTicketCount: 3, OpenTicketCount: 1},
+		{Login: "smith", LastName: "Smith"},
+	}
+	if fmt.Sprint(rowList) != fmt.Sprint(expected) {
+		t.Errorf("unexpected today data %+v, expected %+v", rowList, expected)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetCustomDayData(t *testing.T) {
+	m, mock := newMockProvider(t, []string{"O'Brien", "smith"})
+
+	mock.ExpectQuery(fmt.Sprintf(getCustomDayDataQuery, "?,?")).
+		WithArgs("2021-03-15", "2021-03-16", sqlConnectionTest.OvertimeFieldID, "O'Brien", "smith").
+		WillReturnRows(sqlmock.NewRows([]string{"login", "last_name", "work_time", "over_time"}).
+			AddRow("O'Brien", "O'Brien", 60, 30).
+			AddRow("smith", "Smith", 0, 0))
+
+	rowList, err := m.GetCustomDayData(context.Background(), time.Date(2021, 3, 15, 15, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []otrs.DayStatisticRow{
+		{Login: "O'Brien", LastName: "O'Brien", WorkTime: 60, OverTime: 30},
+		{Login: "smith", LastName: "Smith"},
+	}
+	if fmt.Sprint(rowList) != fmt.Sprint(expected) {
+		t.Errorf("unexpected day data %+v, expected %+v", rowList, expected)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetAccountedTimeDetails(t *testing.T) {
+	m, mock := newMockProvider(t, []string{"O'Brien"})
+
+	mock.ExpectQuery(getAccountedTimeDetailsQuery).
+		WithArgs(sqlConnectionTest.OvertimeFieldID, "2021-03-15", "2021-03-16", "O'Brien").
+		WillReturnRows(sqlmock.NewRows([]string{"tn", "title", "name", "article_id", "time_unit", "overtime"}).
+			AddRow("2021031510000071", "Printer", "Support", 71, 15, true))
+
+	entryList, err := m.GetAccountedTimeDetails(context.Background(), "O'Brien", time.Date(2021, 3, 15, 0, 0, 0, 0, time.Local))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []otrs.AccountedTimeEntry{{TicketNumber: "2021031510000071", Title: "Printer", Queue: "Support", ArticleID: 71, Minutes: 15, IsOverTime: true}}
+	if fmt.Sprint(entryList) != fmt.Sprint(expected) {
+		t.Errorf("unexpected details %+v, expected %+v", entryList, expected)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConnectionErrorClosesConnection(t *testing.T) {
+	m, mock := newMockProvider(t, []string{"O'Brien"})
+
+	mock.ExpectQuery(getAccountedTimeDetailsQuery).WillReturnError(errors.New("syntax error"))
+	mock.ExpectQuery(getAccountedTimeDetailsQuery).WillReturnError(io.ErrUnexpectedEOF)
+
+	_, err := m.GetAccountedTimeDetails(context.Background(), "O'Brien", time.Now())
+	if err == nil || !m.conn.IsOpened() {
+		t.Fatalf("query error must keep connection, got error '%v'", err)
+	}
+	_, err = m.GetAccountedTimeDetails(context.Background(), "O'Brien", time.Now())
+	if !errors.Is(err, io.ErrUnexpectedEOF) || m.conn.IsOpened() {
+		t.Errorf("connection error must close connection, got error '%v'", err)
+	}
+}
+
+func TestGetCustomDayDataOnDaylightSavingEnd(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	m, mock := newMockProvider(t, []string{"O'Brien"})
+
+	// 2021.10.31 lasts 25 hours in Berlin.
+	mock.ExpectQuery(fmt.Sprintf(getCustomDayDataQuery, "?")).
+		WithArgs("2021-10-31", "2021-11-01", sqlConnectionTest.OvertimeFieldID, "O'Brien").
+		WillReturnRows(sqlmock.NewRows([]string{"login", "last_name", "work_time", "over_time"}))
+
+	_, err = m.GetCustomDayData(context.Background(), time.Date(2021, 10, 31, 0, 30, 0, 0, location))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
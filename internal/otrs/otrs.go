@@ -1,18 +1,37 @@
 package otrs
 
-import "time"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
 
+// Returned by provider constructors when OTRS can't be reached.
+// Provider is still usable and connects again on next query.
+var ErrUnavailable = errors.New("OTRS is unavailable")
+
+// Queries are canceled when ctx is done, e.g. on service shutdown.
 type Provider interface {
 	// Get today data from OTRS BD.
-	GetTodayData() ([]DayStatisticRow, error)
+	GetTodayData(ctx context.Context) ([]DayStatisticRow, error)
 	// Get accounted work time and overtime for specified day.
-	GetCustomDayData(day time.Time) ([]DayStatisticRow, error)
+	GetCustomDayData(ctx context.Context, day time.Time) ([]DayStatisticRow, error)
+	// Get accounted work time and overtime per user for every day in [from, to) period.
+	// Days without accounted time are not returned.
+	GetRangeData(ctx context.Context, from, to time.Time) ([]RangeStatisticRow, error)
+	// Get accounted time entries of one user for specified day.
+	GetAccountedTimeDetails(ctx context.Context, login string, day time.Time) ([]AccountedTimeEntry, error)
+	// Get accounted time per user and category (queue, service, customer company) for every day in [from, to) period.
+	GetCategoryData(ctx context.Context, from, to time.Time) ([]CategoryStatisticRow, error)
 	// Close DB connection.
 	Stop() error
 }
 
 // Used for get today data.
+// Users are identified by OTRS login, LastName is used for display only and may be empty.
 type DayStatisticRow struct {
+	Login                string
 	LastName             string
 	WorkTime             int
 	OverTime             int
@@ -20,3 +39,143 @@ type DayStatisticRow struct {
 	LockedTicketCount    int
 	OpenTicketCount      int
 }
+
+// Accounted work time and overtime of one user on one day.
+type RangeStatisticRow struct {
+	Day      time.Time
+	Login    string
+	WorkTime int
+	OverTime int
+}
+
+// One time accounting entry. Used for drill-down of accounted time.
+type AccountedTimeEntry struct {
+	TicketNumber string
+	Title        string
+	Queue        string
+	ArticleID    int
+	Minutes      int
+	IsOverTime   bool
+}
+
+// Categories used for accounted time aggregation.
+const (
+	CategoryQueue    = "queue"
+	CategoryService  = "service"
+	CategoryCustomer = "customer"
+)
+
+// Accounted time of one user in one category on one day.
+// Name is queue name, service name or customer company depending on Category.
+type CategoryStatisticRow struct {
+	Day      time.Time
+	Login    string
+	Category string
+	Name     string
+	WorkTime int
+	OverTime int
+}
+
+// Accounted time of one user on one day for tickets with equal queue, service and customer company.
+// Used by providers as intermediate result for category aggregation.
+type TicketGroupRow struct {
+	Day      time.Time
+	Login    string
+	Queue    string
+	Service  string
+	Customer string
+	WorkTime int
+	OverTime int
+}
+
+// Split ticket group rows into category rows. Sum time of rows with equal day, user, category and name.
+// Rows without service or customer company are not included into the corresponding category.
+func AggregateByCategory(groupList []TicketGroupRow) []CategoryStatisticRow {
+	type key struct {
+		day                   time.Time
+		login, category, name string
+	}
+	index := make(map[key]int, len(groupList)*3)
+	rowList := make([]CategoryStatisticRow, 0, len(groupList)*3)
+	for _, group := range groupList {
+		for category, name := range map[string]string{
+			CategoryQueue:    group.Queue,
+			CategoryService:  group.Service,
+			CategoryCustomer: group.Customer,
+		} {
+			if name == "" {
+				continue
+			}
+			k := key{day: group.Day, login: group.Login, category: category, name: name}
+			i, ok := index[k]
+			if !ok {
+				i = len(rowList)
+				index[k] = i
+				rowList = append(rowList, CategoryStatisticRow{Day: group.Day, Login: group.Login, Category: category, Name: name})
+			}
+			rowList[i].WorkTime += group.WorkTime
+			rowList[i].OverTime += group.OverTime
+		}
+	}
+	return rowList
+}
+
+// Ticket state types from OTRS ticket_state_type table.
+const (
+	StateTypeNew             = "new"
+	StateTypeOpen            = "open"
+	StateTypeClosed          = "closed"
+	StateTypePendingReminder = "pending reminder"
+	StateTypePendingAuto     = "pending auto"
+	StateTypeMerged          = "merged"
+	StateTypeRemoved         = "removed"
+)
+
+// Define which ticket state types are counted as closed and open in today data.
+type StateClassification struct {
+	Closed []string
+	Open   []string
+}
+
+// Return classification used when state types are not configured.
+func DefaultStateClassification() StateClassification {
+	return StateClassification{
+		Closed: []string{StateTypeClosed, StateTypeMerged},
+		Open:   []string{StateTypeOpen},
+	}
+}
+
+// Check that classification contain only known state types and closed state types are specified.
+func (sc StateClassification) Validate() error {
+	if len(sc.Closed) < 1 {
+		return errors.New("closed ticket state types must contain at least one state type")
+	}
+	for _, stateType := range append(append([]string{}, sc.Closed...), sc.Open...) {
+		switch stateType {
+		case StateTypeNew, StateTypeOpen, StateTypeClosed, StateTypePendingReminder, StateTypePendingAuto, StateTypeMerged, StateTypeRemoved:
+		default:
+			return fmt.Errorf("unknown ticket state type '%s'", stateType)
+		}
+	}
+	return nil
+}
+
+// Check if state type counted as closed.
+func (sc StateClassification) IsClosed(stateType string) bool {
+	return contains(sc.Closed, stateType)
+}
+
+// Check if state type counted as open.
+func (sc StateClassification) IsOpen(stateType string) bool {
+	return contains(sc.Open, stateType)
+}
+
+// Check if list contain value.
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,31 +1,41 @@
 package postgre
 
 import (
-	"database/sql"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs"
-	_ "github.com/lib/pq"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs/sqlConnection"
+	"github.com/lib/pq"
 	"time"
 )
 
+// Queries take day start as $1, next day start as $2 and user login list as $3.
+// Today data query also takes closed state types as $4 and open state types as $5.
 const (
-	dayFormatLayout = "2006_01_02" // Day format layout used in queries.
+	// Get ID and object type of dynamic field by name.
+	getDynamicFieldQuery = `
+select id, object_type
+	from dynamic_field
+	where name = $1
+;
+`
 
 	// Get accounted time and ticket statistic.
 	getTodayDataQuery = `
 select
+	users.login,
 	users.last_name,
 	coalesce(ta.sum, 0) as time,
-	t."NotClosed",
-	t."Locked",
-	t."Open"
+	coalesce(t."NotClosed", 0),
+	coalesce(t."Locked", 0),
+	coalesce(t."Open", 0)
 from
 	(select create_by, sum(time_unit)::numeric::integer
 		from time_accounting
 		where
-				create_time >= to_date('%s', 'YYYY_MM_DD')
-			and create_time <  to_date('%s', 'YYYY_MM_DD')
+				create_time >= $1::timestamp
+			and create_time <  $2::timestamp
 		group by create_by
 	) as ta
 	right outer join
@@ -34,21 +44,24 @@ from
 		(select
 			user_id,
 			count(*) as "Locked",
-			count(*) filter (where ticket_state_id not in (2,3,9,15)) as "NotClosed",
-			count(*) filter (where ticket_state_id = 4) as "Open"
+			count(*) filter (where not tst.name = any($4::text[])) as "NotClosed",
+			count(*) filter (where tst.name = any($5::text[])) as "Open"
 			from ticket
+				join ticket_state as ts on ts.id = ticket.ticket_state_id
+				join ticket_state_type as tst on tst.id = ts.type_id
 			where
 				ticket_lock_id != 1
 		group by user_id
 		) as t on t.user_id = users.id
-	where last_name in (%s)
-	order by last_name
+	where login = any($3::text[])
+	order by login
 ;
 `
 
 	// Get accounted work time and overtime.
 	getCustomDayDataQuery = `
 select
+	users.login,
 	users.last_name,
 	coalesce(sum(time_unit)::integer, 0) as "time",
 	coalesce(overtime.value_int, 0) as "overTime"
@@ -56,128 +69,176 @@ from
 	(select create_by, time_unit, article_id
 		from time_accounting
 		where
-				create_time >= to_date('%s', 'YYYY_MM_DD')
-			and create_time <  to_date('%s', 'YYYY_MM_DD')
+				create_time >= $1::timestamp
+			and create_time <  $2::timestamp
 	) as ta
 	left join
 	(SELECT object_id, value_int
 		FROM public.dynamic_field_value
-		where field_id = 87
+		where field_id = $4
  	) as overtime on overtime.object_id = ta.article_id 
 	right outer join
 		users on ta.create_by = users.id
-	where last_name in (%s)
-	group by last_name, overtime.value_int
-	order by last_name
+	where login = any($3::text[])
+	group by login, last_name, overtime.value_int
+	order by login
+;
+`
+
+	// Get accounted work time and overtime grouped by day and user.
+	getRangeDataQuery = `
+select
+	to_char(ta.create_time, 'YYYY-MM-DD') as day,
+	users.login,
+	coalesce(sum(ta.time_unit) filter (where coalesce(overtime.value_int, 0) = 0), 0)::numeric::integer as "time",
+	coalesce(sum(ta.time_unit) filter (where coalesce(overtime.value_int, 0) != 0), 0)::numeric::integer as "overTime"
+from
+	time_accounting as ta
+	join users on users.id = ta.create_by
+	left join dynamic_field_value as overtime on overtime.object_id = ta.article_id
+		and overtime.field_id = $4
+where
+		ta.create_time >= $1::timestamp
+	and ta.create_time <  $2::timestamp
+	and users.login = any($3::text[])
+group by 1, 2
+order by 1, 2
+;
+`
+
+	// Get time accounting entries of one user.
+	getAccountedTimeDetailsQuery = `
+select
+	ticket.tn,
+	ticket.title,
+	queue.name,
+	coalesce(ta.article_id, 0),
+	ta.time_unit::numeric::integer,
+	coalesce(overtime.value_int, 0) != 0
+from
+	time_accounting as ta
+	join users on users.id = ta.create_by
+	join ticket on ticket.id = ta.ticket_id
+	join queue on queue.id = ticket.queue_id
+	left join dynamic_field_value as overtime on overtime.object_id = ta.article_id
+		and overtime.field_id = $4
+where
+		ta.create_time >= $1::timestamp
+	and ta.create_time <  $2::timestamp
+	and users.login = $3
+order by ta.create_time
+;
+`
+
+	// Get accounted work time and overtime grouped by day, user, queue, service and customer company.
+	getCategoryDataQuery = `
+select
+	to_char(ta.create_time, 'YYYY-MM-DD') as day,
+	users.login,
+	queue.name,
+	coalesce(service.name, ''),
+	coalesce(customer_company.name, ticket.customer_id, ''),
+	coalesce(sum(ta.time_unit) filter (where coalesce(overtime.value_int, 0) = 0), 0)::numeric::integer as "time",
+	coalesce(sum(ta.time_unit) filter (where coalesce(overtime.value_int, 0) != 0), 0)::numeric::integer as "overTime"
+from
+	time_accounting as ta
+	join users on users.id = ta.create_by
+	join ticket on ticket.id = ta.ticket_id
+	join queue on queue.id = ticket.queue_id
+	left join service on service.id = ticket.service_id
+	left join customer_company on customer_company.customer_id = ticket.customer_id
+	left join dynamic_field_value as overtime on overtime.object_id = ta.article_id
+		and overtime.field_id = $4
+where
+		ta.create_time >= $1::timestamp
+	and ta.create_time <  $2::timestamp
+	and users.login = any($3::text[])
+group by 1, 2, 3, 4, 5
 ;
 `
 )
 
 // Implement otrs Provider.
-// DB is a connection to postgres DB that contains OTRS data.
+// Connection to postgres DB that contains OTRS data is opened on first query
+// and reopened after connection loss, e.g. OTRS DB restart.
 type Postgre struct {
-	DB       *sql.DB
-	UserList string
+	UserList []string
+	States   otrs.StateClassification
+	conn     *sqlConnection.Connection // Shared by copies of provider.
 }
 
-// Row contain data for one user.
-type OtrsDataRow struct {
-	LastName             string
-	TimeAccounted        int
-	AllTicketCount       int
-	NotClosedTicketCount int
-	OpenTicketCount      int
-}
+// Postgres error code of query canceled by context or by administrator.
+const queryCanceledCode = "57014"
 
 // Initialise and return OTRS DB connector.
-func NewProvider(host, port, user, password, dbName, sslMode string, userList []string) (Postgre, error) {
+func NewProvider(ctx context.Context, host, port, user, password, dbName, sslMode, overtimeField string, userList []string, states otrs.StateClassification) (Postgre, error) {
 	// Construct DB connection string.
 	dbConnectionString := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		host, port, user, password, dbName, sslMode,
 	)
 
-	// Construct user list string for DB query.
-	ul, err := constructUserList(userList)
-	if err != nil {
-		return Postgre{}, err
+	// Return error if empty slice provided.
+	if len(userList) < 1 {
+		return Postgre{}, errors.New("user list must contain at least one user")
 	}
-
-	// Connect to DB.
-	db, err := OpenDB(dbConnectionString)
+	err := states.Validate()
 	if err != nil {
 		return Postgre{}, err
 	}
 
 	var p Postgre
-	p.DB = db
-	p.UserList = ul
-
-	return p, nil
-}
-
-// Construct user list string for DB query.
-func constructUserList(userList []string) (string, error) {
-	// Return error if empty slice provided.
-	if len(userList) < 1 {
-		return "", errors.New("user list must contain at least one user")
-	}
+	p.UserList = userList
+	p.States = states
+	p.conn = sqlConnection.New("postgres", dbConnectionString, overtimeField, getDynamicFieldQuery, isConnectionError)
 
-	// Construct user list string.
-	formattedUserList := ""
-	for _, user := range userList {
-		formattedUserList = fmt.Sprintf("%s'%s',", formattedUserList, user)
+	// Connect to DB. Provider is usable on connection error and connects again on next query.
+	_, _, err = p.conn.Get(ctx)
+	if err != nil {
+		return p, fmt.Errorf("%w: %v", otrs.ErrUnavailable, err)
 	}
 
-	// Truncate last comma.
-	formattedUserList = formattedUserList[:len(formattedUserList)-1]
-
-	return formattedUserList, nil
+	return p, nil
 }
 
-// Connect to DB.
-func OpenDB(dbConnectionString string) (*sql.DB, error) {
-	// open database
-	db, err := sql.Open("postgres", dbConnectionString)
-	if err != nil {
-		return nil, err
-	}
-
-	// check db
-	err = db.Ping()
-	if err != nil {
-		return nil, err
+// Check if postgres error means lost connection to DB.
+// Class 08 is connection exception, class 57 is operator intervention (e.g. DB shutdown) except canceled query.
+func isConnectionError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code.Class() == "08" || pqErr.Code.Class() == "57" && pqErr.Code != queryCanceledCode
 	}
-
-	return db, nil
+	return false
 }
 
 // Get today data from OTRS BD.
-func (p Postgre) GetTodayData() ([]otrs.DayStatisticRow, error) {
-	// Assemble Query string.
-	today := time.Now().Format(dayFormatLayout)
-	tomorrow := time.Now().Add(time.Hour * 24).Format(dayFormatLayout)
-	query := fmt.Sprintf(getTodayDataQuery, today, tomorrow, p.UserList)
-
+func (p Postgre) GetTodayData(ctx context.Context) ([]otrs.DayStatisticRow, error) {
 	// Query for data.
-	rowList, err := p.DB.Query(query)
+	today := truncateToDay(time.Now())
+	tomorrow := today.AddDate(0, 0, 1)
+	db, _, err := p.conn.Get(ctx)
 	if err != nil {
 		return nil, err
 	}
+	rowList, err := db.QueryContext(ctx, getTodayDataQuery, today, tomorrow, pq.Array(p.UserList), pq.Array(p.States.Closed), pq.Array(p.States.Open))
+	if err != nil {
+		return nil, p.conn.Check(err)
+	}
 	defer rowList.Close()
 
 	// Collect data from from query result.
-	var lastName string
+	var login, lastName string
 	var timeAccounted, notClosedTicketCount, lockedTicketCount, openTicketCount int
 	var otrsData = make([]otrs.DayStatisticRow, 0, 32)
 	for rowList.Next() {
-		err = rowList.Scan(&lastName, &timeAccounted, &notClosedTicketCount, &lockedTicketCount, &openTicketCount)
+		err = rowList.Scan(&login, &lastName, &timeAccounted, &notClosedTicketCount, &lockedTicketCount, &openTicketCount)
 		if err != nil {
 			return nil, err
 		}
 		// Append current row.
 		otrsData = append(otrsData,
 			otrs.DayStatisticRow{
+				Login:                login,
 				LastName:             lastName,
 				WorkTime:             timeAccounted,
 				NotClosedTicketCount: notClosedTicketCount,
@@ -186,42 +247,139 @@ func (p Postgre) GetTodayData() ([]otrs.DayStatisticRow, error) {
 			})
 	}
 
-	return otrsData, nil
+	return otrsData, p.conn.Check(rowList.Err())
 }
 
 // Get accounted work time and overtime for specified day.
-func (p Postgre) GetCustomDayData(day time.Time) ([]otrs.DayStatisticRow, error) {
-	// Assemble Query string.
-	dayStart := day.Format(dayFormatLayout)
-	dayEnd := day.Add(time.Hour * 24).Format(dayFormatLayout)
-	query := fmt.Sprintf(getCustomDayDataQuery, dayStart, dayEnd, p.UserList)
-
+func (p Postgre) GetCustomDayData(ctx context.Context, day time.Time) ([]otrs.DayStatisticRow, error) {
 	// Query for data.
-	rowList, err := p.DB.Query(query)
+	dayStart := truncateToDay(day)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	db, fieldID, err := p.conn.Get(ctx)
 	if err != nil {
 		return nil, err
 	}
+	rowList, err := db.QueryContext(ctx, getCustomDayDataQuery, dayStart, dayEnd, pq.Array(p.UserList), fieldID)
+	if err != nil {
+		return nil, p.conn.Check(err)
+	}
 	defer rowList.Close()
 
 	// Collect data from from query result.
-	var lastName string
+	var login, lastName string
 	var workTime, overTimeMark int
 	var otrsData = make([]otrs.DayStatisticRow, 0, 32)
 	for rowList.Next() {
-		err = rowList.Scan(&lastName, &workTime, &overTimeMark)
+		err = rowList.Scan(&login, &lastName, &workTime, &overTimeMark)
 		if err != nil {
 			return nil, err
 		}
 		// Aggregate data based on the presence of the overtime mark.
-		otrsData = addTime(otrsData, lastName, workTime, overTimeMark)
+		otrsData = addTime(otrsData, login, lastName, workTime, overTimeMark)
 	}
 
-	return otrsData, nil
+	return otrsData, p.conn.Check(rowList.Err())
+}
+
+// Get accounted work time and overtime per user for every day in [from, to) period.
+func (p Postgre) GetRangeData(ctx context.Context, from, to time.Time) ([]otrs.RangeStatisticRow, error) {
+	// Query for data.
+	db, fieldID, err := p.conn.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rowList, err := db.QueryContext(ctx, getRangeDataQuery, truncateToDay(from), truncateToDay(to), pq.Array(p.UserList), fieldID)
+	if err != nil {
+		return nil, p.conn.Check(err)
+	}
+	defer rowList.Close()
+
+	// Collect data from from query result.
+	var day string
+	var otrsData = make([]otrs.RangeStatisticRow, 0, 64)
+	for rowList.Next() {
+		var row otrs.RangeStatisticRow
+		err = rowList.Scan(&day, &row.Login, &row.WorkTime, &row.OverTime)
+		if err != nil {
+			return nil, err
+		}
+		row.Day, err = time.ParseInLocation("2006-01-02", day, from.Location())
+		if err != nil {
+			return nil, err
+		}
+		otrsData = append(otrsData, row)
+	}
+
+	return otrsData, p.conn.Check(rowList.Err())
+}
+
+// Get accounted time entries of one user for specified day.
+func (p Postgre) GetAccountedTimeDetails(ctx context.Context, login string, day time.Time) ([]otrs.AccountedTimeEntry, error) {
+	// Query for data.
+	dayStart := truncateToDay(day)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	db, fieldID, err := p.conn.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rowList, err := db.QueryContext(ctx, getAccountedTimeDetailsQuery, dayStart, dayEnd, login, fieldID)
+	if err != nil {
+		return nil, p.conn.Check(err)
+	}
+	defer rowList.Close()
+
+	// Collect data from from query result.
+	var entryList = make([]otrs.AccountedTimeEntry, 0, 32)
+	for rowList.Next() {
+		var entry otrs.AccountedTimeEntry
+		err = rowList.Scan(&entry.TicketNumber, &entry.Title, &entry.Queue, &entry.ArticleID, &entry.Minutes, &entry.IsOverTime)
+		if err != nil {
+			return nil, err
+		}
+		entryList = append(entryList, entry)
+	}
+
+	return entryList, p.conn.Check(rowList.Err())
+}
+
+// Get accounted time per user and category for every day in [from, to) period.
+func (p Postgre) GetCategoryData(ctx context.Context, from, to time.Time) ([]otrs.CategoryStatisticRow, error) {
+	// Query for data.
+	db, fieldID, err := p.conn.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rowList, err := db.QueryContext(ctx, getCategoryDataQuery, truncateToDay(from), truncateToDay(to), pq.Array(p.UserList), fieldID)
+	if err != nil {
+		return nil, p.conn.Check(err)
+	}
+	defer rowList.Close()
+
+	// Collect data from from query result.
+	var day string
+	var groupList = make([]otrs.TicketGroupRow, 0, 64)
+	for rowList.Next() {
+		var group otrs.TicketGroupRow
+		err = rowList.Scan(&day, &group.Login, &group.Queue, &group.Service, &group.Customer, &group.WorkTime, &group.OverTime)
+		if err != nil {
+			return nil, err
+		}
+		group.Day, err = time.ParseInLocation("2006-01-02", day, from.Location())
+		if err != nil {
+			return nil, err
+		}
+		groupList = append(groupList, group)
+	}
+	if err = rowList.Err(); err != nil {
+		return nil, p.conn.Check(err)
+	}
+
+	return otrs.AggregateByCategory(groupList), nil
 }
 
 // TODO - change DB query and remove current function.
 // Aggregate data based on the presence of the overtime mark.
-func addTime(data []otrs.DayStatisticRow, lastName string, time, overTimeMark int) []otrs.DayStatisticRow {
+func addTime(data []otrs.DayStatisticRow, login, lastName string, time, overTimeMark int) []otrs.DayStatisticRow {
 	// Define type of provided time.
 	var workTime, overTime int
 	if overTimeMark == 0 {
@@ -236,6 +394,7 @@ func addTime(data []otrs.DayStatisticRow, lastName string, time, overTimeMark in
 	if len(data) == 0 {
 		data = append(data,
 			otrs.DayStatisticRow{
+				Login:    login,
 				LastName: lastName,
 				WorkTime: workTime,
 				OverTime: overTime,
@@ -243,11 +402,12 @@ func addTime(data []otrs.DayStatisticRow, lastName string, time, overTimeMark in
 		return data
 	}
 
-	// If current LastName and LastName from last slice element are not equal, add new element, else update last element.
+	// If current Login and Login from last slice element are not equal, add new element, else update last element.
 	lastElement := len(data) - 1
-	if data[lastElement].LastName != lastName {
+	if data[lastElement].Login != login {
 		data = append(data,
 			otrs.DayStatisticRow{
+				Login:    login,
 				LastName: lastName,
 				WorkTime: workTime,
 				OverTime: overTime,
@@ -260,11 +420,16 @@ func addTime(data []otrs.DayStatisticRow, lastName string, time, overTimeMark in
 	return data
 }
 
+// Return start of the day in the same location.
+// Passed as timestamp without time zone, so local midnight is compared with OTRS local time.
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
 // Close DB connection.
 func (p Postgre) Stop() error {
-	err := p.DB.Close()
-	if err != nil {
-		return err
+	if p.conn == nil {
+		return nil
 	}
-	return nil
+	return p.conn.Close()
 }
@@ -0,0 +1,111 @@
+package postgre
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs/sqlConnection/sqlConnectionTest"
+	"io"
+	"testing"
+	"time"
+)
+
+// Return provider connected to mock DB.
+func newMockProvider(t *testing.T, userList []string) (Postgre, sqlmock.Sqlmock) {
+	conn, mock := sqlConnectionTest.NewMock(t, isConnectionError)
+	return Postgre{UserList: userList, States: otrs.DefaultStateClassification(), conn: conn}, mock
+}
+
+func TestGetTodayData(t *testing.T) {
+	p, mock := newMockProvider(t, []string{"O'Brien", "smith"})
+
+	mock.ExpectQuery(getTodayDataQuery).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), `{"O'Brien","smith"}`, `{"closed","merged"}`, `{"open"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"login", "last_name", "time", "NotClosed", "Locked", "Open"}).
+			AddRow("O'Brien", "O'Brien", 90, 2, 3, 1).
+			AddRow("smith", "Smith", 0, 0, 0, 0))
+
+	rowList, err := p.GetTodayData(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []otrs.DayStatisticRow{
+		{Login: "O'Brien", LastName: "O'Brien", WorkTime: 90, NotClosedTicketCount: 2, LockedTicketCount: 3, OpenTicketCount: 1},
+		{Login: "smith", LastName: "Smith"},
+	}
+	if fmt.Sprint(rowList) != fmt.Sprint(expected) {
+		t.Errorf("unexpected today data %+v, expected %+v", rowList, expected)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetCustomDayData(t *testing.T) {
+	p, mock := newMockProvider(t, []string{"O'Brien", "smith"})
+
+	dayStart := time.Date(2021, 3, 15, 0, 0, 0, 0, time.Local)
+	mock.ExpectQuery(getCustomDayDataQuery).
+		WithArgs(dayStart, dayStart.AddDate(0, 0, 1), `{"O'Brien","smith"}`, sqlConnectionTest.OvertimeFieldID).
+		WillReturnRows(sqlmock.NewRows([]string{"login", "last_name", "time", "overTime"}).
+			AddRow("O'Brien", "O'Brien", 60, 0).
+			AddRow("O'Brien", "O'Brien", 30, 1).
+			AddRow("smith", "Smith", 0, 0))
+
+	rowList, err := p.GetCustomDayData(context.Background(), dayStart.Add(time.Hour*15))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []otrs.DayStatisticRow{
+		{Login: "O'Brien", LastName: "O'Brien", WorkTime: 60, OverTime: 30},
+		{Login: "smith", LastName: "Smith"},
+	}
+	if fmt.Sprint(rowList) != fmt.Sprint(expected) {
+		t.Errorf("unexpected day data %+v, expected %+v", rowList, expected)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetAccountedTimeDetails(t *testing.T) {
+	p, mock := newMockProvider(t, []string{"O'Brien"})
+
+	dayStart := time.Date(2021, 3, 15, 0, 0, 0, 0, time.Local)
+	mock.ExpectQuery(getAccountedTimeDetailsQuery).
+		WithArgs(dayStart, dayStart.AddDate(0, 0, 1), "O'Brien", sqlConnectionTest.OvertimeFieldID).
+		WillReturnRows(sqlmock.NewRows([]string{"tn", "title", "name", "article_id", "time_unit", "overtime"}).
+			AddRow("2021031510000071", "Printer", "Support", 71, 15, false))
+
+	entryList, err := p.GetAccountedTimeDetails(context.Background(), "O'Brien", dayStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []otrs.AccountedTimeEntry{{TicketNumber: "2021031510000071", Title: "Printer", Queue: "Support", ArticleID: 71, Minutes: 15}}
+	if fmt.Sprint(entryList) != fmt.Sprint(expected) {
+		t.Errorf("unexpected details %+v, expected %+v", entryList, expected)
+	}
+	if err = mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRowErrorIsReturned(t *testing.T) {
+	p, mock := newMockProvider(t, []string{"O'Brien"})
+
+	mock.ExpectQuery(getRangeDataQuery).
+		WillReturnRows(sqlmock.NewRows([]string{"day", "login", "time", "overTime"}).
+			AddRow("2021-03-15", "O'Brien", 60, 0).
+			AddRow("2021-03-16", "O'Brien", 30, 0).
+			RowError(1, io.ErrUnexpectedEOF))
+
+	_, err := p.GetRangeData(context.Background(), time.Date(2021, 3, 15, 0, 0, 0, 0, time.Local), time.Date(2021, 3, 17, 0, 0, 0, 0, time.Local))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected row error, got '%v'", err)
+	}
+	if p.conn.IsOpened() {
+		t.Error("connection error must close connection")
+	}
+}
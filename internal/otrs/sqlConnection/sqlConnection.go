@@ -0,0 +1,162 @@
+package sqlConnection
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// Connection to OTRS DB and ID of article dynamic field which marks overtime, resolved on connect.
+// Connection is opened on first use and reopened after connection loss, e.g. OTRS DB restart.
+// Safe for concurrent use.
+type Connection struct {
+	mx                sync.Mutex
+	driverName        string
+	dsn               string
+	overtimeField     string
+	dynamicFieldQuery string           // Take dynamic field name and return its ID and object type.
+	isConnectionError func(error) bool // Driver specific check of lost connection.
+	db                *sql.DB          // Nil if not connected.
+	overtimeFieldID   int
+}
+
+// Return not connected connection.
+// Driver specific error classifier is called after generic network errors are checked.
+func New(driverName, dsn, overtimeField, dynamicFieldQuery string, isConnectionError func(error) bool) *Connection {
+	return &Connection{
+		driverName:        driverName,
+		dsn:               dsn,
+		overtimeField:     overtimeField,
+		dynamicFieldQuery: dynamicFieldQuery,
+		isConnectionError: isConnectionError,
+	}
+}
+
+// Return connection that uses already opened DB, e.g. mock DB in tests.
+// Connection isn't reopened after connection loss.
+func NewOpened(db *sql.DB, overtimeFieldID int, isConnectionError func(error) bool) *Connection {
+	return &Connection{db: db, overtimeFieldID: overtimeFieldID, isConnectionError: isConnectionError}
+}
+
+// Return opened DB connection and overtime field ID. Connect to DB if not connected.
+func (c *Connection) Get(ctx context.Context) (*sql.DB, int, error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.db != nil {
+		return c.db, c.overtimeFieldID, nil
+	}
+	if c.driverName == "" {
+		return nil, 0, errors.New("connection is closed")
+	}
+
+	db, err := Open(ctx, c.driverName, c.dsn)
+	if err != nil {
+		return nil, 0, fmt.Errorf("can't connect to OTRS DB '%w'", err)
+	}
+
+	// Resolve overtime dynamic field.
+	fieldID, err := c.getOvertimeFieldID(ctx, db)
+	if err != nil {
+		db.Close()
+		return nil, 0, err
+	}
+
+	c.db = db
+	c.overtimeFieldID = fieldID
+	return db, fieldID, nil
+}
+
+// Close connection if err is a connection error, so next query connects again. Return err unchanged.
+func (c *Connection) Check(err error) error {
+	if !c.IsConnectionError(err) {
+		return err
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if c.db != nil {
+		c.db.Close()
+		c.db = nil
+	}
+	return err
+}
+
+// Check if connection is opened.
+func (c *Connection) IsOpened() bool {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.db != nil
+}
+
+// Close connection.
+func (c *Connection) Close() error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.db == nil {
+		return nil
+	}
+	err := c.db.Close()
+	c.db = nil
+	return err
+}
+
+// Check if error means lost connection to DB.
+func (c *Connection) IsConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if c.isConnectionError != nil && c.isConnectionError(err) {
+		return true
+	}
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
+
+// Find overtime dynamic field by name and check that it is attached to articles.
+func (c *Connection) getOvertimeFieldID(ctx context.Context, db *sql.DB) (int, error) {
+	name := c.overtimeField
+	if name == "" {
+		return 0, errors.New("overtime dynamic field name must be specified")
+	}
+
+	var id int
+	var objectType string
+	err := db.QueryRowContext(ctx, c.dynamicFieldQuery, name).Scan(&id, &objectType)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("overtime dynamic field '%s' not found in OTRS", name)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if objectType != "Article" {
+		return 0, fmt.Errorf("overtime dynamic field '%s' is attached to '%s' objects instead of articles", name, objectType)
+	}
+
+	return id, nil
+}
+
+// Connect to DB.
+func Open(ctx context.Context, driverName, dsn string) (*sql.DB, error) {
+	// open database
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// check db
+	err = db.PingContext(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
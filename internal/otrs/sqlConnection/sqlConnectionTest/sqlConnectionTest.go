@@ -0,0 +1,23 @@
+package sqlConnectionTest
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs/sqlConnection"
+	"testing"
+)
+
+// Overtime dynamic field ID of mock connection.
+const OvertimeFieldID = 5
+
+// Return connection to mock DB for provider tests. Queries are matched literally.
+// Mock DB is closed when test ends.
+func NewMock(t *testing.T, isConnectionError func(error) bool) (*sqlConnection.Connection, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return sqlConnection.NewOpened(db, OvertimeFieldID, isConnectionError), mock
+}
@@ -0,0 +1,158 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron-like schedule: "minute hour day-of-month month day-of-week".
+// Every field supports "*", single values, ranges "1-5", steps "*/15" and "1-30/2" and lists "1,15,30".
+// Day of week is 0-6 (0 is Sunday), 7 is also accepted as Sunday.
+// As in cron, if both day of month and day of week are restricted, day matches if any of them matches.
+type Schedule struct {
+	spec    string
+	minute  uint64 // Bit set of allowed values.
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	domStar bool
+	dowStar bool
+}
+
+// Bounds of schedule fields.
+var fieldBounds = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Parse cron-like schedule.
+func Parse(spec string) (Schedule, error) {
+	fieldList := strings.Fields(spec)
+	if len(fieldList) != len(fieldBounds) {
+		return Schedule{}, fmt.Errorf("schedule '%s' must have 5 fields: minute hour day-of-month month day-of-week", spec)
+	}
+
+	bitList := make([]uint64, len(fieldList))
+	for i, field := range fieldList {
+		bits, err := parseField(field, fieldBounds[i].min, fieldBounds[i].max)
+		if err != nil {
+			return Schedule{}, fmt.Errorf("schedule '%s' %s field: %w", spec, fieldBounds[i].name, err)
+		}
+		bitList[i] = bits
+	}
+
+	// Sunday may be specified as 7.
+	dow := bitList[4]
+	if dow&(1<<7) != 0 {
+		dow = dow&^(1<<7) | 1
+	}
+
+	return Schedule{
+		spec:    spec,
+		minute:  bitList[0],
+		hour:    bitList[1],
+		dom:     bitList[2],
+		month:   bitList[3],
+		dow:     dow,
+		domStar: fieldList[2] == "*",
+		dowStar: fieldList[4] == "*",
+	}, nil
+}
+
+// Parse one schedule field into bit set of allowed values.
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in '%s'", part)
+			}
+		}
+
+		first, last := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			i := strings.Index(rangePart, "-")
+			var err1, err2 error
+			first, err1 = strconv.Atoi(rangePart[:i])
+			last, err2 = strconv.Atoi(rangePart[i+1:])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range '%s'", part)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value '%s'", part)
+			}
+			first, last = value, value
+			if step > 1 {
+				last = max
+			}
+		}
+		if first < min || last > max || first > last {
+			return 0, fmt.Errorf("'%s' is out of range %v-%v", part, min, max)
+		}
+
+		for value := first; value <= last; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+// Return the first minute after t matching the schedule.
+// Return zero time if no matching time found within 5 years (e.g. "0 0 31 2 *").
+func (s Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Check day of month and day of week with cron rules.
+func (s Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Return schedule in source format.
+func (s Schedule) String() string {
+	return s.spec
+}
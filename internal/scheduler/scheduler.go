@@ -0,0 +1,257 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Errors returned by Scheduler methods.
+var (
+	ErrJobRunning  = errors.New("job is already running")
+	ErrJobNotFound = errors.New("job not found")
+	ErrStopped     = errors.New("scheduler is stopped")
+)
+
+// Run registered jobs by their schedules.
+// Run of a job is skipped if previous run of the same job is not finished yet.
+// Failed run is retried according to retry policy.
+// Time and result of the last run are kept for every job.
+type Scheduler struct {
+	mx      sync.Mutex
+	jobList map[string]*job
+	started bool
+	stopped bool // Set by Wait, new runs are refused.
+	retry   Backoff
+	running sync.WaitGroup // Runs in progress.
+}
+
+// Retry policy of failed job run.
+// Delay before retry doubles from Initial up to Max, random jitter up to half of delay is subtracted
+// so jobs failed at the same time don't retry at the same time.
+// Attempts is total number of attempts in one run, values less than 2 disable retries.
+type Backoff struct {
+	Attempts int
+	Initial  time.Duration
+	Max      time.Duration
+}
+
+// Return delay before retry which follows failed attempt number attempt (counted from 1).
+func (b Backoff) delay(attempt int) time.Duration {
+	d := b.Initial
+	for i := 1; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		d = b.Max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d - time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// Registered job and its run state.
+type job struct {
+	name     string
+	schedule Schedule
+	run      func(ctx context.Context) error
+	running  bool
+	status   JobStatus
+}
+
+// State of one job.
+type JobStatus struct {
+	Name        string
+	Schedule    string
+	Running     bool
+	LastStart   time.Time // Zero if job never started.
+	LastFinish  time.Time // Zero if job never finished.
+	LastSuccess time.Time // Finish of the last succeeded run, zero if job never succeeded.
+	LastError   string    // Error of the last attempt, empty if it succeeded.
+	Failures    int       // Failed attempts since the last success.
+	NextRun     time.Time
+}
+
+// Create scheduler without jobs. Failed runs are retried according to retry policy.
+func New(retry Backoff) *Scheduler {
+	return &Scheduler{jobList: make(map[string]*job), retry: retry}
+}
+
+// Register job with cron-like schedule (see Parse). Jobs must be registered before Start.
+// Job gets context of Start or Run, it is done when job must be stopped.
+func (s *Scheduler) Add(name, spec string, run func(ctx context.Context) error) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.started {
+		return fmt.Errorf("can't add job '%s' to started scheduler", name)
+	}
+	if _, ok := s.jobList[name]; ok {
+		return fmt.Errorf("job '%s' already registered", name)
+	}
+	s.jobList[name] = &job{
+		name:     name,
+		schedule: schedule,
+		run:      run,
+		status:   JobStatus{Name: name, Schedule: spec},
+	}
+	return nil
+}
+
+// Start goroutine for every registered job. Jobs are not scheduled after ctx is done.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	for _, j := range s.jobList {
+		go s.loop(ctx, j)
+	}
+}
+
+// Refuse new runs and wait until all runs in progress are finished.
+func (s *Scheduler) Wait() {
+	s.mx.Lock()
+	s.stopped = true
+	s.mx.Unlock()
+	s.running.Wait()
+}
+
+// Wait for next scheduled time and run job until ctx is done.
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	for {
+		next := j.schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("Job '%s' schedule '%s' never matches, job stopped", j.name, j.schedule)
+			return
+		}
+		s.mx.Lock()
+		j.status.NextRun = next
+		s.mx.Unlock()
+
+		if !sleep(ctx, time.Until(next)) {
+			return
+		}
+
+		err := s.Run(ctx, j.name)
+		if errors.Is(err, ErrJobRunning) {
+			log.Printf("Job '%s' skipped, previous run is not finished", j.name)
+		}
+	}
+}
+
+// Run job immediately and wait for result, failed attempts are retried with backoff until ctx is done.
+// Return ErrJobRunning if job is already running, ErrJobNotFound if job is not registered
+// and ErrStopped if Wait is already called.
+func (s *Scheduler) Run(ctx context.Context, name string) error {
+	// Runs are counted under lock, so no run is added while Wait is waiting.
+	s.mx.Lock()
+	if s.stopped {
+		s.mx.Unlock()
+		return ErrStopped
+	}
+	if ctx.Err() != nil {
+		s.mx.Unlock()
+		return ctx.Err()
+	}
+	j, ok := s.jobList[name]
+	if !ok {
+		s.mx.Unlock()
+		return fmt.Errorf("%w: '%s'", ErrJobNotFound, name)
+	}
+	if j.running {
+		s.mx.Unlock()
+		return fmt.Errorf("%w: '%s'", ErrJobRunning, name)
+	}
+	j.running = true
+	j.status.LastStart = time.Now()
+	s.running.Add(1)
+	s.mx.Unlock()
+	defer s.running.Done()
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = j.run(ctx)
+		if ctx.Err() != nil {
+			log.Printf("Job '%s' stopped '%v'", name, err)
+			break
+		}
+		s.recordAttempt(j, err)
+		if err == nil {
+			break
+		}
+		if attempt >= s.retry.Attempts {
+			log.Printf("Job '%s' failed '%v'", name, err)
+			break
+		}
+		delay := s.retry.delay(attempt)
+		log.Printf("Job '%s' attempt %v failed '%v', retry in %v", name, attempt, err, delay.Round(time.Second))
+		if !sleep(ctx, delay) {
+			break
+		}
+	}
+
+	s.mx.Lock()
+	j.running = false
+	j.status.LastFinish = time.Now()
+	s.mx.Unlock()
+
+	return err
+}
+
+// Store result of one attempt, so failure is visible while job is retried.
+func (s *Scheduler) recordAttempt(j *job, err error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	if err != nil {
+		j.status.LastError = err.Error()
+		j.status.Failures++
+		return
+	}
+	j.status.LastError = ""
+	j.status.Failures = 0
+	j.status.LastSuccess = time.Now()
+}
+
+// Return state of all jobs ordered by name.
+func (s *Scheduler) Status() []JobStatus {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	statusList := make([]JobStatus, 0, len(s.jobList))
+	for _, j := range s.jobList {
+		status := j.status
+		status.Running = j.running
+		statusList = append(statusList, status)
+	}
+	sort.Slice(statusList, func(i, j int) bool {
+		return statusList[i].Name < statusList[j].Name
+	})
+	return statusList
+}
+
+// Sleep for duration d. Return false if ctx is done earlier.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunRetriesFailedAttempts(t *testing.T) {
+	s := New(Backoff{Attempts: 3, Initial: time.Millisecond, Max: time.Millisecond})
+	attempts := 0
+	err := s.Add("job", "* * * * *", func(ctx context.Context) error {
+		attempts++
+		if attempts < 2 {
+			return errors.New("OTRS is down")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.Run(context.Background(), "job")
+	if err != nil || attempts != 2 {
+		t.Fatalf("expected success on attempt 2, got '%v' after %v attempts", err, attempts)
+	}
+	status := s.Status()[0]
+	if status.Failures != 0 || status.LastError != "" || status.LastSuccess.IsZero() || status.Running {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	err = s.Run(context.Background(), "unknown")
+	if !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected ErrJobNotFound, got '%v'", err)
+	}
+}
+
+func TestWaitWaitsForRunsAndRefusesNewRuns(t *testing.T) {
+	s := New(Backoff{})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	err := s.Add("job", "* * * * *", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		_ = s.Run(context.Background(), "job")
+	}()
+	<-started
+
+	err = s.Run(context.Background(), "job")
+	if !errors.Is(err, ErrJobRunning) {
+		t.Errorf("expected ErrJobRunning, got '%v'", err)
+	}
+
+	waited := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("Wait returned while job is running")
+	case <-time.After(time.Millisecond * 20):
+	}
+	close(release)
+	<-waited
+
+	err = s.Run(context.Background(), "job")
+	if !errors.Is(err, ErrStopped) {
+		t.Errorf("expected ErrStopped after Wait, got '%v'", err)
+	}
+}
+
+func TestRunConcurrentWithWait(t *testing.T) {
+	s := New(Backoff{})
+	for _, name := range []string{"a", "b", "c", "d"} {
+		err := s.Add(name, "* * * * *", func(ctx context.Context) error { return nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b", "c", "d"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				err := s.Run(context.Background(), name)
+				if errors.Is(err, ErrStopped) {
+					return
+				}
+			}
+		}(name)
+	}
+	s.Wait()
+	wg.Wait()
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/httpServer"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"strconv"
+	"time"
+)
+
+// Cell color for days when user is absent. Matches the color in the HTML template.
+const absenceColor = "absence-grid-col"
+
+// Display names of absence types.
+var absenceTypeName = map[string]string{
+	internalDB.AbsenceTypeVacation:     "Отпуск",
+	internalDB.AbsenceTypeSickLeave:    "Больничный",
+	internalDB.AbsenceTypeBusinessTrip: "Командировка",
+}
+
+// Return function for usage in HTTP server.
+// Function returns absences of all users which intersect [from, to] period. Current month is used if period is not specified.
+func (s *Service) AbsencesConnector() func(from, to string) (httpServer.Absences, error) {
+	return func(from, to string) (httpServer.Absences, error) {
+		now := time.Now()
+		if from == "" {
+			from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).Format("2006.01.02")
+		}
+		if to == "" {
+			to = time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, time.Local).Format("2006.01.02")
+		}
+		fromDay, err := parseRequestDate(from)
+		if err != nil {
+			return httpServer.Absences{}, err
+		}
+		toDay, err := parseRequestDate(to)
+		if err != nil {
+			return httpServer.Absences{}, err
+		}
+
+		absenceList, err := s.DB.GetAbsenceByDayRange(fromDay, toDay)
+		if errors.Is(err, internalDB.ErrInvalidRange) {
+			return httpServer.Absences{}, fmt.Errorf("%w: %v", httpServer.ErrBadRequest, err)
+		}
+		if err != nil {
+			return httpServer.Absences{}, err
+		}
+
+		absences := httpServer.Absences{
+			From:  from,
+			To:    to,
+			Users: s.getUserOrder(),
+			Data:  make([]httpServer.AbsenceRow, 0, len(absenceList)),
+		}
+		for _, a := range absenceList {
+			absences.Data = append(absences.Data, httpServer.AbsenceRow{
+				ID:        a.ID,
+				Login:     a.Login,
+				LastName:  s.displayName(a.Login),
+				From:      unixDayToDate(a.FromDay),
+				To:        unixDayToDate(a.ToDay),
+				Type:      a.Type,
+				TypeName:  absenceTypeName[a.Type],
+				Comment:   a.Comment,
+				Author:    a.Author,
+				CreatedAt: a.CreatedAt.Format("2006.01.02 15:04:05"),
+			})
+		}
+		return absences, nil
+	}
+}
+
+// Add user absence into internal DB. Return ID of added absence.
+func (s *Service) addAbsence(a httpServer.AbsenceRequest) (int64, error) {
+	fromDay, err := parseRequestDate(a.From)
+	if err != nil {
+		return 0, err
+	}
+	toDay, err := parseRequestDate(a.To)
+	if err != nil {
+		return 0, err
+	}
+	if !s.isConfiguredUser(a.Login) {
+		return 0, fmt.Errorf("%w: unknown user '%s'", httpServer.ErrBadRequest, a.Login)
+	}
+
+	id, err := s.DB.AddAbsence(internalDB.Absence{
+		Login:   a.Login,
+		FromDay: fromDay,
+		ToDay:   toDay,
+		Type:    a.Type,
+		Comment: a.Comment,
+		Author:  a.Author,
+	})
+	if errors.Is(err, internalDB.ErrInvalidAbsence) {
+		return 0, fmt.Errorf("%w: %v", httpServer.ErrBadRequest, err)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("can't add absence '%w'", err)
+	}
+	return id, nil
+}
+
+// Remove user absence from internal DB.
+func (s *Service) removeAbsence(id string) error {
+	absenceID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid absence id '%s'", httpServer.ErrBadRequest, id)
+	}
+
+	err = s.DB.RemoveAbsence(absenceID)
+	if errors.Is(err, internalDB.ErrNotFound) {
+		return fmt.Errorf("%w: %v", httpServer.ErrNotFound, err)
+	}
+	if err != nil {
+		return fmt.Errorf("can't remove absence '%w'", err)
+	}
+	return nil
+}
+
+// Return absence type of every user for every absence day in [fromDay, toDay] range.
+func (s *Service) absenceByLoginAndDay(fromDay, toDay int64) (map[string]map[int64]string, error) {
+	absenceList, err := s.DB.GetAbsenceByDayRange(fromDay, toDay)
+	if err != nil {
+		return nil, err
+	}
+	absence := make(map[string]map[int64]string)
+	for _, a := range absenceList {
+		if absence[a.Login] == nil {
+			absence[a.Login] = make(map[int64]string)
+		}
+		// Only days of requested range are needed.
+		first, last := a.FromDay, a.ToDay
+		if first < fromDay {
+			first = fromDay
+		}
+		if last > toDay {
+			last = toDay
+		}
+		for day := first; day <= last; day++ {
+			absence[a.Login][day] = a.Type
+		}
+	}
+	return absence, nil
+}
+
+// Check if user with provided login is configured.
+func (s *Service) isConfiguredUser(login string) bool {
+	for _, user := range s.Cfg.UserList {
+		if user.Login == login {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/calendar"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/config"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB/gormDB"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB/gromSqlite3"
+	"log"
+	"path/filepath"
+)
+
+// Copy all data from sqlite file into internal DB configured in "config.yaml".
+// Configured internal DB must be PostgreSQL and must be empty.
+// Both DB are migrated to the latest schema version before copy.
+func CopySQLite(fileName string) error {
+	var srv Service
+	var err error
+
+	srv.Cfg, err = config.ReadConfigFromYAMLFile("config.yaml")
+	if err != nil {
+		return err
+	}
+	if srv.Cfg.InternalDB.Driver != "postgres" {
+		return fmt.Errorf("target internal DB driver must be 'postgres', configured '%s'", srv.Cfg.InternalDB.Driver)
+	}
+
+	src, err := gromSqlite3.NewDB(fileName, srv.internalDBUserList())
+	if err != nil {
+		return fmt.Errorf("open sqlite DB '%s' failed '%w'", fileName, err)
+	}
+	defer src.Close()
+	dst, err := srv.newInternalDB()
+	if err != nil {
+		return fmt.Errorf("open target DB failed '%w'", err)
+	}
+	defer dst.Close()
+
+	log.Printf("Copy data from '%s' into PostgreSQL DB '%s'", fileName, srv.Cfg.InternalDB.DBName)
+	err = gormDB.Copy(src, dst)
+	if err != nil {
+		return err
+	}
+	log.Printf("Copy finished")
+	return nil
+}
+
+// Import production calendar from file into internal DB configured in "config.yaml".
+// Difference between file and stored overridden days is printed. Changes are written only if apply is true.
+// Overridden days missing in file (e.g. corporate days off) are kept.
+func ImportCalendar(fileName string, apply bool) error {
+	var srv Service
+	var err error
+
+	srv.Cfg, err = config.ReadConfigFromYAMLFile("config.yaml")
+	if err != nil {
+		return err
+	}
+
+	dayList, err := calendar.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("read calendar '%s' failed '%w'", fileName, err)
+	}
+	if len(dayList) == 0 {
+		fmt.Println("Calendar is empty")
+		return nil
+	}
+
+	srv.DB, err = srv.newInternalDB()
+	if err != nil {
+		return err
+	}
+	defer srv.DB.Close()
+
+	// Collect stored overridden days of calendar period.
+	fromDay, err := dateToUnixDay(dayList[0].Date.Format("2006.01.02"))
+	if err != nil {
+		return err
+	}
+	toDay, err := dateToUnixDay(dayList[len(dayList)-1].Date.Format("2006.01.02"))
+	if err != nil {
+		return err
+	}
+	storedList, err := srv.DB.GetOverrideByDaySequence(fromDay, toDay-fromDay+1)
+	if err != nil {
+		return err
+	}
+	stored := make(map[int64]internalDB.WorkdayOverride, len(storedList))
+	for _, wo := range storedList {
+		stored[wo.Day] = wo
+	}
+
+	// Print difference and collect changes.
+	changeList := make([]internalDB.WorkdayOverride, 0, len(dayList))
+	imported := make(map[int64]bool, len(dayList))
+	for _, day := range dayList {
+		unixDay, err := dateToUnixDay(day.Date.Format("2006.01.02"))
+		if err != nil {
+			return err
+		}
+		imported[unixDay] = true
+		old, ok := stored[unixDay]
+		switch {
+		case !ok:
+			fmt.Printf("+ %s %s %s\n", day.Date.Format("2006.01.02"), day.Type, day.Reason)
+		case old.Type != day.Type || old.Reason != day.Reason:
+			fmt.Printf("~ %s %s %s -> %s %s\n", day.Date.Format("2006.01.02"), old.Type, old.Reason, day.Type, day.Reason)
+		default:
+			continue
+		}
+		changeList = append(changeList, internalDB.WorkdayOverride{
+			Day:    unixDay,
+			Type:   day.Type,
+			Norm:   old.Norm,
+			Reason: day.Reason,
+			Author: fmt.Sprint("import ", filepath.Base(fileName)),
+		})
+	}
+	for _, wo := range storedList {
+		if !imported[wo.Day] {
+			fmt.Printf("= %s %s %s (missing in calendar, kept)\n", unixDayToDate(wo.Day), wo.Type, wo.Reason)
+		}
+	}
+
+	if len(changeList) == 0 {
+		fmt.Println("No changes")
+		return nil
+	}
+	if !apply {
+		fmt.Printf("%v changes, run with -apply to write them\n", len(changeList))
+		return nil
+	}
+	err = srv.DB.SetWorkdayOverrideList(changeList)
+	if err != nil {
+		return fmt.Errorf("store changes failed '%w'", err)
+	}
+	fmt.Printf("%v changes applied\n", len(changeList))
+	return nil
+}
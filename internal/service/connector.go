@@ -1,15 +1,24 @@
 package service
 
 import (
+	"errors"
+	"fmt"
 	"github.com/Sarraksh/OTRS-time-accounting/internal/httpServer"
 	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // Uses in week calculation and table visualisation.
 type Workday struct {
-	Number    int64 // Day number since 1970.01.01 .
-	IsWorkday bool  // True if workday false if day off.
+	Number    int64  // Day number since 1970.01.01 .
+	IsWorkday bool   // True if workday false if day off.
+	Norm      int64  // Work time norm in minutes, 0 for day off.
+	Type      string // Override type, empty if day is not overridden.
+	Reason    string // Override reason.
 }
 
 // Uses in table visualisation.
@@ -20,72 +29,278 @@ type User struct {
 }
 
 // Calculate workweek from day list and overridden day list.
-func CalculateWorkWeek(dayList, overriddenDayList []int64) []Workday {
+func CalculateWorkWeek(dayList []int64, overrideList []internalDB.WorkdayOverride) []Workday {
 	wd := make([]Workday, 0, 7) // Initial empty slice for week day list.
-	var isOverridden bool       // Indicate if current day overridden.
 	for i, day := range dayList {
-		isOverridden = false                                   // Reset indicator for new day
-		wd = append(wd, Workday{Number: day, IsWorkday: true}) // Add new day as workday.
-		for _, overriddenDay := range overriddenDayList {
-			if day == overriddenDay {
-				isOverridden = true
-			}
-		}
+		// By default 1-5 day is workdays, 6 and 7 is days off.
+		wd = append(wd, newWorkday(day, i < 5, overrideList))
+	}
+	return wd
+}
 
-		// Change day if it day off. By default 1-5 day is workdays, 6 and 7 is days off.
-		// If 1-5 day overridden, it is considered as day off.
-		// If 6 or 7 day overridden, it is considered as working day.
-		if i < 5 && isOverridden == true || i >= 5 && isOverridden == false {
-			wd[i].IsWorkday = false
+// Calculate day type and norm. Overridden day type is defined by override type.
+// Shortened day norm is one hour less than default, if custom norm is not specified.
+func newWorkday(day int64, isDefaultWorkday bool, overrideList []internalDB.WorkdayOverride) Workday {
+	wd := Workday{Number: day, IsWorkday: isDefaultWorkday}
+	for _, wo := range overrideList {
+		if day != wo.Day {
+			continue
+		}
+		wd.IsWorkday = internalDB.IsWorkdayType(wo.Type)
+		wd.Type = wo.Type
+		wd.Reason = wo.Reason
+		if wd.IsWorkday && wo.Norm > 0 {
+			wd.Norm = wo.Norm
+			return wd
 		}
+		if wo.Type == internalDB.DayTypeShortened {
+			wd.Norm = defaultDayNorm - shortenedDayReduction
+			return wd
+		}
+	}
+	if wd.IsWorkday {
+		wd.Norm = defaultDayNorm
 	}
 	return wd
 }
 
+// Return cell color for accounted time compared with norm.
+// Time less than 80% of norm is bad, less than norm is average. Any time is good for day off.
+func timeColor(time, norm int64) string {
+	switch {
+	case time < norm*badNormPercent/100:
+		return "bad-grid-col"
+	case time < norm:
+		return "average-grid-col"
+	default:
+		return "good-grid-col"
+	}
+}
+
 // Return function for usage in HTTP server.
 func (s *Service) WeekConnector(weekOffset int64) func() (httpServer.WeekStatistic, error) {
 	return func() (httpServer.WeekStatistic, error) {
 		WeekDayList := getWeekDayList(weekOffset)
-		OverriddenDayList, err := s.DB.GetOverrideByDaySequence(WeekDayList[0], 7)
+		overrideList, err := s.DB.GetOverrideByDaySequence(WeekDayList[0], 7)
 		if err != nil {
 			return httpServer.WeekStatistic{}, err
 		}
 
-		dayList := CalculateWorkWeek(WeekDayList, OverriddenDayList)
+		dayList := CalculateWorkWeek(WeekDayList, overrideList)
 
 		var ws httpServer.WeekStatistic
-		userOrder := getUserOrder()
+		userOrder := s.getUserOrder()
 		for _, user := range userOrder {
 			ws.Data = append(ws.Data, httpServer.WeekStatisticRow{User: user, TimeAccounted: make([]httpServer.TimeAccounted, 8)})
 		}
 
-		ws = collectWeekData(s.DB, dayList, ws)
+		absence, err := s.absenceByLoginAndDay(WeekDayList[0], WeekDayList[len(WeekDayList)-1])
+		if err != nil {
+			return httpServer.WeekStatistic{}, err
+		}
+
+		ws, err = collectWeekData(s.DB, dayList, absence, ws)
+		if err != nil {
+			return httpServer.WeekStatistic{}, err
+		}
 
 		return ws, nil
 	}
 }
 
+// Return function for usage in HTTP server.
+// Function collects accounted time entries of one user for one day directly from OTRS.
+func (s *Service) DetailsConnector() func(login, date string) (httpServer.DayDetails, error) {
+	return func(login, date string) (httpServer.DayDetails, error) {
+		day, err := time.ParseInLocation("2006.01.02", date, time.Local)
+		if err != nil {
+			return httpServer.DayDetails{}, fmt.Errorf("%w: invalid date '%s', expected format is '2006.01.02'", httpServer.ErrBadRequest, date)
+		}
+
+		entryList, err := s.OTRS.GetAccountedTimeDetails(s.ctx, login, day)
+		if err != nil {
+			return httpServer.DayDetails{}, err
+		}
+
+		details := httpServer.DayDetails{
+			Login:    login,
+			LastName: s.displayName(login),
+			Date:     date,
+			Data:     make([]httpServer.DayDetailsRow, 0, len(entryList)),
+		}
+		for _, entry := range entryList {
+			if entry.IsOverTime {
+				details.OverTime = details.OverTime + entry.Minutes
+			} else {
+				details.WorkTime = details.WorkTime + entry.Minutes
+			}
+			details.Data = append(details.Data, httpServer.DayDetailsRow{
+				TicketNumber: entry.TicketNumber,
+				Title:        entry.Title,
+				Queue:        entry.Queue,
+				ArticleID:    entry.ArticleID,
+				Minutes:      entry.Minutes,
+				IsOverTime:   entry.IsOverTime,
+			})
+		}
+
+		return details, nil
+	}
+}
+
+// Return function for usage in HTTP server.
+// Function sums stored category data for [from, to] period. Current month is used if period is not specified.
+func (s *Service) ReportConnector() func(from, to string) (httpServer.Report, error) {
+	return func(from, to string) (httpServer.Report, error) {
+		now := time.Now()
+		if from == "" {
+			from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local).Format("2006.01.02")
+		}
+		if to == "" {
+			to = now.Format("2006.01.02")
+		}
+		fromDay, err := parseRequestDate(from)
+		if err != nil {
+			return httpServer.Report{}, err
+		}
+		toDay, err := parseRequestDate(to)
+		if err != nil {
+			return httpServer.Report{}, err
+		}
+
+		ctList, err := s.DB.GetCategoryTimeByDayRange(fromDay, toDay)
+		if errors.Is(err, internalDB.ErrInvalidRange) {
+			return httpServer.Report{}, fmt.Errorf("%w: %v", httpServer.ErrBadRequest, err)
+		}
+		if err != nil {
+			return httpServer.Report{}, err
+		}
+
+		report := httpServer.Report{From: from, To: to}
+		customerIndex := make(map[string]int)
+		for _, ct := range ctList {
+			row := httpServer.ReportRow{
+				LastName: s.displayName(ct.Login),
+				Name:     ct.Name,
+				WorkTime: ct.WorkTime,
+				OverTime: ct.OverTime,
+				Total:    ct.WorkTime + ct.OverTime,
+			}
+			switch ct.Category {
+			case otrs.CategoryQueue:
+				report.UserQueue = append(report.UserQueue, row)
+			case otrs.CategoryService:
+				report.UserService = append(report.UserService, row)
+			case otrs.CategoryCustomer:
+				// Sum time of all users for customer company.
+				i, ok := customerIndex[ct.Name]
+				if !ok {
+					i = len(report.Customer)
+					customerIndex[ct.Name] = i
+					report.Customer = append(report.Customer, httpServer.ReportRow{Name: ct.Name})
+				}
+				report.Customer[i].WorkTime = report.Customer[i].WorkTime + row.WorkTime
+				report.Customer[i].OverTime = report.Customer[i].OverTime + row.OverTime
+				report.Customer[i].Total = report.Customer[i].Total + row.Total
+			}
+		}
+
+		sortReportRows(report.UserQueue)
+		sortReportRows(report.UserService)
+		sortReportRows(report.Customer)
+
+		return report, nil
+	}
+}
+
+// Return function for usage in HTTP server.
+// Function reads change history of stored accounted time of one user for one day.
+func (s *Service) HistoryConnector() func(login, date string) (httpServer.History, error) {
+	return func(login, date string) (httpServer.History, error) {
+		day, err := parseRequestDate(date)
+		if err != nil {
+			return httpServer.History{}, err
+		}
+
+		changeList, err := s.DB.GetAccountedTimeHistory(day, login)
+		if err != nil {
+			return httpServer.History{}, err
+		}
+
+		history := httpServer.History{
+			LastName: s.displayName(login),
+			Date:     date,
+			Data:     make([]httpServer.HistoryRow, 0, len(changeList)),
+		}
+		for _, change := range changeList {
+			history.Data = append(history.Data, httpServer.HistoryRow{
+				ChangedAt:   change.ChangedAt.Format("2006.01.02 15:04:05"),
+				Source:      change.Source,
+				IsNew:       change.IsNew,
+				OldWorkTime: change.OldWorkTime,
+				OldOverTime: change.OldOverTime,
+				NewWorkTime: change.NewWorkTime,
+				NewOverTime: change.NewOverTime,
+			})
+		}
+
+		return history, nil
+	}
+}
+
+// Sort report rows by last name, then by total time in descending order.
+func sortReportRows(rowList []httpServer.ReportRow) {
+	sort.Slice(rowList, func(i, j int) bool {
+		if rowList[i].LastName != rowList[j].LastName {
+			return rowList[i].LastName < rowList[j].LastName
+		}
+		return rowList[i].Total > rowList[j].Total
+	})
+}
+
 // Collect data and assemble in correct order for show on web page.
-func collectWeekData(db internalDB.Provider, dayList []Workday, ws httpServer.WeekStatistic) httpServer.WeekStatistic {
+// All data is read from internal DB by one query. Days without stored data are shown as zero time.
+// Absence days (absence type by login and day number) are excluded from user norm and shown as absence cells.
+func collectWeekData(db internalDB.Provider, dayList []Workday, absence map[string]map[int64]string, ws httpServer.WeekStatistic) (httpServer.WeekStatistic, error) {
+	loginList := make([]string, 0, len(ws.Data))
+	for _, row := range ws.Data {
+		loginList = append(loginList, row.User.Login)
+	}
+	atList, err := db.GetAccountedTimeByDayRange(dayList[0].Number, dayList[len(dayList)-1].Number, loginList)
+	if err != nil {
+		return httpServer.WeekStatistic{}, err
+	}
+
+	// Group stored data by login and day number.
+	atByLogin := make(map[string]map[int64]internalDB.AccountedTime, len(loginList))
+	for _, at := range atList {
+		if atByLogin[at.Login] == nil {
+			atByLogin[at.Login] = make(map[int64]internalDB.AccountedTime, len(dayList))
+		}
+		atByLogin[at.Login][at.Day] = at
+	}
+
 	var workTime, overTime int64
+	userWeekNorm := make([]int64, len(ws.Data))
 	for rowIndex, row := range ws.Data {
 		for columnIndex, day := range dayList {
-			// Get time data from internal DB and store into web data struct.
-			workTime, overTime = db.GetAccountedTimeByDayAndLastname(day.Number, row.User.LastName)
+			// Get time data and store into web data struct.
+			workTime = atByLogin[row.User.Login][day.Number].WorkTime
+			overTime = atByLogin[row.User.Login][day.Number].OverTime
 			ws.Data[rowIndex].TimeAccounted[columnIndex+1].Time = ws.Data[rowIndex].TimeAccounted[columnIndex+1].Time + workTime
 			ws.Data[rowIndex].TimeAccounted[columnIndex+1].Overtime = ws.Data[rowIndex].TimeAccounted[columnIndex+1].Overtime + overTime
 			ws.Data[rowIndex].TimeAccounted[0].Time = ws.Data[rowIndex].TimeAccounted[0].Time + workTime + overTime
+			ws.Data[rowIndex].TimeAccounted[columnIndex+1].Date = unixDayToDate(day.Number)
 
 			// Define color for cell with accounted time for current day.
-			switch {
-			case !day.IsWorkday:
-				ws.Data[rowIndex].TimeAccounted[columnIndex+1].Color = "good-grid-col"
-			case ws.Data[rowIndex].TimeAccounted[columnIndex+1].Time+ws.Data[rowIndex].TimeAccounted[columnIndex+1].Overtime < 240:
-				ws.Data[rowIndex].TimeAccounted[columnIndex+1].Color = "bad-grid-col"
-			case ws.Data[rowIndex].TimeAccounted[columnIndex+1].Time+ws.Data[rowIndex].TimeAccounted[columnIndex+1].Overtime < 300:
-				ws.Data[rowIndex].TimeAccounted[columnIndex+1].Color = "average-grid-col"
-			default:
-				ws.Data[rowIndex].TimeAccounted[columnIndex+1].Color = "good-grid-col"
+			// Absence day is not counted in user norm.
+			if absenceType, ok := absence[row.User.Login][day.Number]; ok {
+				ws.Data[rowIndex].TimeAccounted[columnIndex+1].Color = absenceColor
+				ws.Data[rowIndex].TimeAccounted[columnIndex+1].Absence = absenceTypeName[absenceType]
+			} else {
+				ws.Data[rowIndex].TimeAccounted[columnIndex+1].Color = timeColor(
+					ws.Data[rowIndex].TimeAccounted[columnIndex+1].Time+ws.Data[rowIndex].TimeAccounted[columnIndex+1].Overtime, day.Norm)
+				userWeekNorm[rowIndex] = userWeekNorm[rowIndex] + day.Norm
 			}
 
 			// Controls the overtime visibility. Don't show if zero.
@@ -95,14 +310,19 @@ func collectWeekData(db internalDB.Provider, dayList []Workday, ws httpServer.We
 		}
 	}
 
-	// Calculate workday count and define colors for table title.
-	var workdayCount int64 = 0
+	// Define colors for table title.
 	ws.HeaderColor = make([]string, 8, 8)
 	ws.HeaderColor[0] = "themed-grid-col"
+	ws.HeaderTitle = make([]string, 8, 8)
 	for i, day := range dayList {
+		if day.Type != "" {
+			ws.HeaderTitle[i+1] = strings.TrimSpace(fmt.Sprint(dayTypeName[day.Type], " ", day.Reason))
+		}
+		if day.IsWorkday && day.Norm != defaultDayNorm {
+			ws.HeaderTitle[i+1] = strings.TrimSpace(fmt.Sprintf("%s (норма %v мин.)", ws.HeaderTitle[i+1], day.Norm))
+		}
 		if day.IsWorkday {
 			ws.HeaderColor[i+1] = "work-day-grid-col"
-			workdayCount++
 		} else {
 			ws.HeaderColor[i+1] = "day-off-grid-col"
 		}
@@ -110,17 +330,61 @@ func collectWeekData(db internalDB.Provider, dayList []Workday, ws httpServer.We
 
 	// Define color for cell with accounted time for week.
 	for rowIndex, _ := range ws.Data {
-		switch {
-		case ws.Data[rowIndex].TimeAccounted[0].Time < (240 * workdayCount):
-			ws.Data[rowIndex].TimeAccounted[0].Color = "bad-grid-col"
-		case ws.Data[rowIndex].TimeAccounted[0].Time < (300 * workdayCount):
-			ws.Data[rowIndex].TimeAccounted[0].Color = "average-grid-col"
-		default:
-			ws.Data[rowIndex].TimeAccounted[0].Color = "good-grid-col"
+		ws.Data[rowIndex].TimeAccounted[0].Color = timeColor(ws.Data[rowIndex].TimeAccounted[0].Time, userWeekNorm[rowIndex])
+	}
+
+	return ws, nil
+}
+
+// Display names of overridden day types.
+var dayTypeName = map[string]string{
+	internalDB.DayTypeHoliday:            "Праздник",
+	internalDB.DayTypeTransferredWorkday: "Перенесённый рабочий день",
+	internalDB.DayTypeShortened:          "Сокращённый день",
+	internalDB.DayTypeCorporateDayOff:    "Корпоративный выходной",
+}
+
+// Return function for usage in HTTP server.
+// Function returns overridden days of [from, to] period.
+func (s *Service) WDOListConnector() func(from, to string) ([]httpServer.DayOverride, error) {
+	return func(from, to string) ([]httpServer.DayOverride, error) {
+		fromDay, err := parseRequestDate(from)
+		if err != nil {
+			return nil, err
+		}
+		toDay, err := parseRequestDate(to)
+		if err != nil {
+			return nil, err
+		}
+		if fromDay > toDay {
+			return nil, fmt.Errorf("%w: from '%s' is after to '%s'", httpServer.ErrBadRequest, from, to)
 		}
+
+		woList, err := s.DB.GetOverrideByDaySequence(fromDay, toDay-fromDay+1)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]httpServer.DayOverride, 0, len(woList))
+		for _, wo := range woList {
+			result = append(result, httpServer.DayOverride{
+				Date:      unixDayToDate(wo.Day),
+				Type:      wo.Type,
+				Norm:      normString(wo.Norm),
+				Reason:    wo.Reason,
+				Author:    wo.Author,
+				CreatedAt: wo.CreatedAt.Format("2006.01.02 15:04:05"),
+			})
+		}
+		return result, nil
 	}
+}
 
-	return ws
+// Format custom norm for web API. Default norm is empty string.
+func normString(norm int64) string {
+	if norm == 0 {
+		return ""
+	}
+	return strconv.FormatInt(norm, 10)
 }
 
 // Return current week day list.
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/httpServer"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/scheduler"
+	"time"
+)
+
+// Names of regular jobs.
+const (
+	jobTodayRefresh          = "todayRefresh"          // Refresh today statistic and today accounted time.
+	jobYesterdayFinalization = "yesterdayFinalization" // Final synchronisation of yesterday after midnight.
+	jobWeeklyResync          = "weeklyResync"          // Re-sync of last days to catch time accounted retroactively.
+	jobReconciliation        = "reconciliation"        // Comparison of stored time with OTRS.
+)
+
+// Default job schedules, re-sync depth and retry policy, used if not set in config.
+const (
+	defaultTodayRefresh          = "*/15 * * * *"
+	defaultYesterdayFinalization = "10 0 * * *"
+	defaultWeeklyResync          = "30 1 * * 1"
+	defaultResyncDays            = 14
+	defaultReconciliation        = "0 3 * * 0"
+	defaultReconcileWeeks        = 4
+	defaultRetryAttempts         = 5
+	defaultRetryDelay            = 30  // Seconds.
+	defaultRetryMaxDelay         = 300 // Seconds.
+)
+
+// Create scheduler with regular synchronisation jobs. Schedules are taken from config.
+func (s *Service) newScheduler() (*scheduler.Scheduler, error) {
+	cfg := s.Cfg.Schedule
+	resyncDays := cfg.ResyncDays
+	if resyncDays <= 0 {
+		resyncDays = defaultResyncDays
+	}
+
+	sch := scheduler.New(scheduler.Backoff{
+		Attempts: intOrDefault(cfg.RetryAttempts, defaultRetryAttempts),
+		Initial:  time.Duration(intOrDefault(cfg.RetryDelay, defaultRetryDelay)) * time.Second,
+		Max:      time.Duration(intOrDefault(cfg.RetryMaxDelay, defaultRetryMaxDelay)) * time.Second,
+	})
+	err := sch.Add(jobTodayRefresh, valueOrDefault(cfg.TodayRefresh, defaultTodayRefresh), s.UpdateTodayStatistic)
+	if err != nil {
+		return nil, err
+	}
+	err = sch.Add(jobYesterdayFinalization, valueOrDefault(cfg.YesterdayFinalization, defaultYesterdayFinalization), func(ctx context.Context) error {
+		return s.GetDayFromOTRSAndStore(ctx, -1, syncSourceNightly)
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = sch.Add(jobWeeklyResync, valueOrDefault(cfg.WeeklyResync, defaultWeeklyResync), func(ctx context.Context) error {
+		today := truncateToDay(time.Now())
+		return s.SyncRange(ctx, today.AddDate(0, 0, -resyncDays), today, syncSourceWeekly)
+	})
+	if err != nil {
+		return nil, err
+	}
+	reconcileWeeks := intOrDefault(cfg.ReconcileWeeks, defaultReconcileWeeks)
+	err = sch.Add(jobReconciliation, valueOrDefault(cfg.Reconciliation, defaultReconciliation), func(ctx context.Context) error {
+		return s.reconcileJob(ctx, reconcileWeeks, cfg.ReconcileApply)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sch, nil
+}
+
+// Return state of regular jobs for web interface.
+func (s *Service) JobsConnector() func() []httpServer.JobStatus {
+	return func() []httpServer.JobStatus {
+		statusList := s.Jobs.Status()
+		jobList := make([]httpServer.JobStatus, 0, len(statusList))
+		for _, status := range statusList {
+			jobList = append(jobList, httpServer.JobStatus{
+				Name:        status.Name,
+				Schedule:    status.Schedule,
+				Running:     status.Running,
+				LastStart:   formatJobTime(status.LastStart),
+				LastFinish:  formatJobTime(status.LastFinish),
+				LastSuccess: formatJobTime(status.LastSuccess),
+				LastError:   status.LastError,
+				Failures:    status.Failures,
+				NextRun:     formatJobTime(status.NextRun),
+			})
+		}
+		return jobList
+	}
+}
+
+// Return state of synchronisation with OTRS for web interface.
+// Data is stale while today refresh fails.
+func (s *Service) SyncStateConnector() func() httpServer.SyncState {
+	return func() httpServer.SyncState {
+		for _, status := range s.Jobs.Status() {
+			if status.Name != jobTodayRefresh {
+				continue
+			}
+			return httpServer.SyncState{
+				Stale:      status.Failures > 0,
+				LastUpdate: formatJobTime(status.LastSuccess),
+				Error:      status.LastError,
+			}
+		}
+		return httpServer.SyncState{}
+	}
+}
+
+// Format job time for web interface, zero time is empty string.
+func formatJobTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006.01.02 15:04:05")
+}
+
+// Return value or default if value is empty.
+func valueOrDefault(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// Return value or default if value is not positive.
+func intOrDefault(value, defaultValue int) int {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/config"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/httpServer"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Report of the last reconciliation job run.
+type reconcileState struct {
+	mx     sync.Mutex
+	report httpServer.Reconciliation
+}
+
+// Compare stored accounted time of the last weeks (till yesterday) with OTRS and return mismatched user-days.
+// Days without stored data and without time in OTRS are not compared (e.g. days before first synchronisation).
+// If apply is true, mismatched time and category data of mismatched days are replaced with OTRS data.
+func (s *Service) Reconcile(ctx context.Context, weeks int, apply bool) (httpServer.Reconciliation, error) {
+	to := truncateToDay(time.Now())
+	from := to.AddDate(0, 0, -7*weeks)
+	report := httpServer.Reconciliation{
+		From:      from.Format("2006.01.02"),
+		To:        to.AddDate(0, 0, -1).Format("2006.01.02"),
+		CheckedAt: time.Now().Format("2006.01.02 15:04:05"),
+		Data:      make([]httpServer.ReconciliationRow, 0),
+	}
+
+	// Collect data from OTRS and internal DB.
+	rangeData, err := s.OTRS.GetRangeData(ctx, from, to)
+	if err != nil {
+		return report, err
+	}
+	otrsTime := make(map[int64]map[string]otrs.RangeStatisticRow)
+	for _, row := range rangeData {
+		unixDay, err := dateToUnixDay(row.Day.Format("2006.01.02"))
+		if err != nil {
+			return report, err
+		}
+		if otrsTime[unixDay] == nil {
+			otrsTime[unixDay] = make(map[string]otrs.RangeStatisticRow)
+		}
+		otrsTime[unixDay][row.Login] = row
+	}
+	fromDay, err := dateToUnixDay(report.From)
+	if err != nil {
+		return report, err
+	}
+	toDay, err := dateToUnixDay(report.To)
+	if err != nil {
+		return report, err
+	}
+	storedList, err := s.DB.GetAccountedTimeByDayRange(fromDay, toDay, s.userList())
+	if err != nil {
+		return report, err
+	}
+	storedTime := make(map[int64]map[string]internalDB.AccountedTime)
+	for _, at := range storedList {
+		if storedTime[at.Day] == nil {
+			storedTime[at.Day] = make(map[string]internalDB.AccountedTime)
+		}
+		storedTime[at.Day][at.Login] = at
+	}
+
+	// Compare every user-day.
+	atList := make([]internalDB.AccountedTime, 0)
+	mismatchedDays := make(map[int64]bool)
+	for day := fromDay; day <= toDay; day++ {
+		for _, login := range s.userList() {
+			actual := otrsTime[day][login]
+			stored, ok := storedTime[day][login]
+			if !ok && actual.WorkTime == 0 && actual.OverTime == 0 {
+				continue
+			}
+			if ok && stored.WorkTime == int64(actual.WorkTime) && stored.OverTime == int64(actual.OverTime) {
+				continue
+			}
+			report.Data = append(report.Data, httpServer.ReconciliationRow{
+				Date:           unixDayToDate(day),
+				Login:          login,
+				LastName:       s.displayName(login),
+				Missing:        !ok,
+				StoredWorkTime: stored.WorkTime,
+				StoredOverTime: stored.OverTime,
+				OTRSWorkTime:   int64(actual.WorkTime),
+				OTRSOverTime:   int64(actual.OverTime),
+			})
+			atList = append(atList, internalDB.AccountedTime{
+				Day:      day,
+				Login:    login,
+				WorkTime: int64(actual.WorkTime),
+				OverTime: int64(actual.OverTime),
+			})
+			mismatchedDays[day] = true
+		}
+	}
+	if !apply || len(atList) == 0 {
+		return report, nil
+	}
+
+	// Replace mismatched time and category data of mismatched days.
+	categoryData, err := s.OTRS.GetCategoryData(ctx, from, to)
+	if err != nil {
+		return report, err
+	}
+	ctByDay := make(map[int64][]internalDB.CategoryTime, len(mismatchedDays))
+	for day := range mismatchedDays {
+		ctByDay[day] = nil
+	}
+	for _, row := range categoryData {
+		unixDay, err := dateToUnixDay(row.Day.Format("2006.01.02"))
+		if err != nil {
+			return report, err
+		}
+		if !mismatchedDays[unixDay] {
+			continue
+		}
+		ctByDay[unixDay] = append(ctByDay[unixDay], internalDB.CategoryTime{
+			Login:    row.Login,
+			Category: row.Category,
+			Name:     row.Name,
+			WorkTime: int64(row.WorkTime),
+			OverTime: int64(row.OverTime),
+		})
+	}
+	err = s.DB.StoreSyncData(atList, ctByDay, syncSourceReconcile)
+	if err != nil {
+		return report, fmt.Errorf("store corrections failed '%w'", err)
+	}
+	report.Applied = true
+
+	return report, nil
+}
+
+// Run reconciliation with configured depth and store report for web interface.
+func (s *Service) reconcileJob(ctx context.Context, weeks int, apply bool) error {
+	report, err := s.Reconcile(ctx, weeks, apply)
+	if err != nil {
+		report.Error = err.Error()
+	}
+	s.reconcile.mx.Lock()
+	s.reconcile.report = report
+	s.reconcile.mx.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if len(report.Data) > 0 {
+		log.Printf("Reconciliation of '%s' - '%s' found %v mismatched user-days, applied: %v",
+			report.From, report.To, len(report.Data), report.Applied)
+	}
+	return nil
+}
+
+// Return function for usage in HTTP server.
+// Function returns report of the last reconciliation.
+func (s *Service) ReconciliationConnector() func() httpServer.Reconciliation {
+	return func() httpServer.Reconciliation {
+		s.reconcile.mx.Lock()
+		defer s.reconcile.mx.Unlock()
+		return s.reconcile.report
+	}
+}
+
+// Compare accounted time of the last weeks stored in internal DB configured in "config.yaml" with OTRS.
+// Mismatched user-days are printed. Corrections are written only if apply is true.
+func Reconcile(weeks int, apply bool) error {
+	var srv Service
+	var err error
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv.Cfg, err = config.ReadConfigFromYAMLFile("config.yaml")
+	if err != nil {
+		return err
+	}
+	if weeks <= 0 {
+		return fmt.Errorf("number of weeks must be positive, got %v", weeks)
+	}
+
+	srv.DB, err = srv.newInternalDB()
+	if err != nil {
+		return err
+	}
+	defer srv.DB.Close()
+	srv.OTRS, err = srv.newOTRSProvider(ctx)
+	if err != nil {
+		return err
+	}
+	defer srv.OTRS.Stop()
+
+	report, err := srv.Reconcile(ctx, weeks, apply)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Reconciliation of '%s' - '%s'\n", report.From, report.To)
+	for _, row := range report.Data {
+		stored := fmt.Sprintf("%v (+%v)", row.StoredWorkTime, row.StoredOverTime)
+		if row.Missing {
+			stored = "missing"
+		}
+		fmt.Printf("~ %s %s stored %s, OTRS %v (+%v)\n", row.Date, row.Login, stored, row.OTRSWorkTime, row.OTRSOverTime)
+	}
+	switch {
+	case len(report.Data) == 0:
+		fmt.Println("No mismatches")
+	case report.Applied:
+		fmt.Printf("%v mismatches corrected\n", len(report.Data))
+	default:
+		fmt.Printf("%v mismatches, run with -apply to correct them\n", len(report.Data))
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/config"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/httpServer"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Days synchronised by one OTRS query during re-sync, so long periods don't load OTRS DB with one huge query.
+const resyncChunkDays = 31
+
+// Progress of the last on-demand re-sync. Only one re-sync runs at a time.
+type resyncState struct {
+	mx       sync.Mutex
+	progress httpServer.ResyncProgress
+}
+
+// Re-sync accounted time of [from, to] days (both included) from OTRS by chunks.
+// Progress is called after every chunk with number of synchronised and total days.
+// Re-sync stops after the chunk in progress when ctx is done.
+func (s *Service) ResyncRange(ctx context.Context, from, to time.Time, source string, progress func(done, total int)) error {
+	from = truncateToDay(from)
+	to = truncateToDay(to).AddDate(0, 0, 1)
+	total := int(to.Sub(from).Hours()/24 + 0.5) // Round, day may be 23 or 25 hours long.
+
+	done := 0
+	for chunkFrom := from; chunkFrom.Before(to); {
+		chunkTo := chunkFrom.AddDate(0, 0, resyncChunkDays)
+		if chunkTo.After(to) {
+			chunkTo = to
+		}
+		err := s.SyncRange(ctx, chunkFrom, chunkTo, source)
+		if err != nil {
+			return fmt.Errorf("re-sync of '%s' - '%s' failed after %v of %v days '%w'",
+				from.Format("2006.01.02"), to.AddDate(0, 0, -1).Format("2006.01.02"), done, total, err)
+		}
+		done += int(chunkTo.Sub(chunkFrom).Hours()/24 + 0.5)
+		progress(done, total)
+		chunkFrom = chunkTo
+	}
+	return nil
+}
+
+// Parse re-sync period. Both dates must be in "2006.01.02" format, from must not be after to and to must not be in future.
+func parseResyncRange(from, to string) (time.Time, time.Time, error) {
+	fromDate, err := time.ParseInLocation("2006.01.02", from, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: invalid date '%s', expected format is '2006.01.02'", httpServer.ErrBadRequest, from)
+	}
+	toDate, err := time.ParseInLocation("2006.01.02", to, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: invalid date '%s', expected format is '2006.01.02'", httpServer.ErrBadRequest, to)
+	}
+	if fromDate.After(toDate) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: period start '%s' is after end '%s'", httpServer.ErrBadRequest, from, to)
+	}
+	if toDate.After(truncateToDay(time.Now())) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: period end '%s' is in future", httpServer.ErrBadRequest, to)
+	}
+	return fromDate, toDate, nil
+}
+
+// Return function for usage in HTTP server.
+// Function starts re-sync of [from, to] period in background and returns its initial progress.
+// If re-sync is already running, return ErrConflict.
+func (s *Service) StartResyncConnector() func(from, to, author string) (httpServer.ResyncProgress, error) {
+	return func(from, to, author string) (httpServer.ResyncProgress, error) {
+		fromDate, toDate, err := parseResyncRange(from, to)
+		if err != nil {
+			return httpServer.ResyncProgress{}, err
+		}
+
+		s.resync.mx.Lock()
+		defer s.resync.mx.Unlock()
+		if s.resync.progress.Running {
+			return s.resync.progress, fmt.Errorf("%w: re-sync of '%s' - '%s' is already running",
+				httpServer.ErrConflict, s.resync.progress.From, s.resync.progress.To)
+		}
+		s.resync.progress = httpServer.ResyncProgress{
+			Running:   true,
+			From:      from,
+			To:        to,
+			Author:    author,
+			StartedAt: time.Now().Format("2006.01.02 15:04:05"),
+		}
+		log.Printf("Re-sync of '%s' - '%s' started by '%s'", from, to, author)
+
+		s.background.Add(1)
+		go func() {
+			defer s.background.Done()
+			err := s.ResyncRange(s.ctx, fromDate, toDate, syncSourceResync, func(done, total int) {
+				s.resync.mx.Lock()
+				s.resync.progress.DoneDays = done
+				s.resync.progress.TotalDays = total
+				s.resync.mx.Unlock()
+			})
+
+			s.resync.mx.Lock()
+			defer s.resync.mx.Unlock()
+			s.resync.progress.Running = false
+			s.resync.progress.FinishedAt = time.Now().Format("2006.01.02 15:04:05")
+			if err != nil {
+				s.resync.progress.Error = err.Error()
+				log.Printf("Re-sync failed '%v'", err)
+				return
+			}
+			log.Printf("Re-sync of '%s' - '%s' finished", from, to)
+		}()
+
+		return s.resync.progress, nil
+	}
+}
+
+// Return function for usage in HTTP server.
+// Function returns progress of the last re-sync.
+func (s *Service) ResyncProgressConnector() func() httpServer.ResyncProgress {
+	return func() httpServer.ResyncProgress {
+		s.resync.mx.Lock()
+		defer s.resync.mx.Unlock()
+		return s.resync.progress
+	}
+}
+
+// Re-sync accounted time of [from, to] period from OTRS into internal DB configured in "config.yaml".
+// Progress is printed after every synchronised chunk.
+func Resync(from, to string) error {
+	var srv Service
+	var err error
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv.Cfg, err = config.ReadConfigFromYAMLFile("config.yaml")
+	if err != nil {
+		return err
+	}
+	fromDate, toDate, err := parseResyncRange(from, to)
+	if err != nil {
+		return err
+	}
+
+	srv.DB, err = srv.newInternalDB()
+	if err != nil {
+		return err
+	}
+	defer srv.DB.Close()
+	srv.OTRS, err = srv.newOTRSProvider(ctx)
+	if err != nil {
+		return err
+	}
+	defer srv.OTRS.Stop()
+
+	fmt.Printf("Re-sync '%s' - '%s'\n", from, to)
+	err = srv.ResyncRange(ctx, fromDate, toDate, syncSourceResync, func(done, total int) {
+		fmt.Printf("%v/%v days synchronised\n", done, total)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("Re-sync finished")
+	return nil
+}
@@ -1,15 +1,28 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/Sarraksh/OTRS-time-accounting/internal/config"
 	"github.com/Sarraksh/OTRS-time-accounting/internal/httpServer"
 	"github.com/Sarraksh/OTRS-time-accounting/internal/httpServer/goviewEcho"
 	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB/gormPostgres"
 	"github.com/Sarraksh/OTRS-time-accounting/internal/internalDB/gromSqlite3"
 	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs/fake"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs/genericInterface"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs/mysql"
 	"github.com/Sarraksh/OTRS-time-accounting/internal/otrs/postgre"
+	"github.com/Sarraksh/OTRS-time-accounting/internal/scheduler"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
 )
 
@@ -21,107 +34,258 @@ type Service struct {
 	OTRS otrs.Provider              // Connection to OTRS data base. Contain SQL query templates.
 	HTTP httpServer.Provider        // Shows data to users and has small API for insert some data.
 	Data *httpServer.TodayStatistic // Struct uses to send data for display by HTTP server. Today statistic.
+	Jobs *scheduler.Scheduler       // Regular synchronisation with OTRS.
+
+	ctx        context.Context // Root context, done on shutdown. Used by operations started from HTTP requests.
+	background sync.WaitGroup  // Operations which run in background: initial backfill and re-sync started from HTTP requests.
+	resync     resyncState     // Progress of on-demand re-sync.
+	reconcile  reconcileState  // Report of the last reconciliation.
 }
 
+const (
+	demoMode       = "demo"                       // Mode without OTRS. Data is generated or read from fixture file.
+	demoDBFileName = "file::memory:?cache=shared" // In-memory internal DB used in demo mode.
+)
+
+// Sources of accounted time changes, stored into change history.
+const (
+	syncSourceStartup   = "startup"   // Synchronisation of last days on service start.
+	syncSourceRegular   = "regular"   // Regular synchronisation of today data.
+	syncSourceNightly   = "nightly"   // Nightly finalization of yesterday data.
+	syncSourceWeekly    = "weekly"    // Weekly re-sync of last days.
+	syncSourceResync    = "resync"    // On-demand re-sync of period by API or command.
+	syncSourceReconcile = "reconcile" // Correction of mismatches found by reconciliation with OTRS.
+)
+
+const (
+	defaultDayNorm        = 300 // Work time norm of workday in minutes.
+	shortenedDayReduction = 60  // Pre-holiday day is one hour shorter.
+	badNormPercent        = 80  // Time less than this percent of norm is bad.
+)
+
+const shutdownTimeout = 30 * time.Second // Time to finish HTTP requests in progress on shutdown.
+
+const morningShift = "M" // Work shift value in config for morning shift. Other values are evening shift.
+
 const (
 	morningShiftColor = "morning-shift-grid-col" // Matches the color in the HTML template.
 	eveningShiftColor = "evening-shift-grid-col" // Matches the color in the HTML template.
 )
 
+// Start service and serve until SIGINT or SIGTERM, then shut down gracefully.
 func Start() {
 	var srv Service
 	var err error
 
+	// Root context is canceled by signal, it stops scheduled jobs and cancels OTRS queries.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv.ctx = ctx
+
 	// Read config from file.
 	srv.Cfg, err = config.ReadConfigFromYAMLFile("config.yaml")
 	if err != nil {
 		log.Printf("Read config from file failed '%v'", err)
 	}
-	log.Printf("'%+v'\n", srv.Cfg)
+	log.Printf("'%+v'\n", srv.Cfg.Redacted())
 
 	// Initialise internal DB.
-	internalDBProvider, err := gromSqlite3.NewDB("")
+	internalDBProvider, err := srv.newInternalDB()
 	if err != nil {
-		log.Printf("Internal DB initialisation error '%v'", err)
+		log.Fatalf("Internal DB initialisation error '%v'", err)
 	}
 	srv.DB = internalDBProvider
 
 	// Initialise OTRS.
-	otrsDB, err := postgre.NewProvider(
-		srv.Cfg.OTRSConnection.Host,
-		srv.Cfg.OTRSConnection.Port,
-		srv.Cfg.OTRSConnection.UserName,
-		srv.Cfg.OTRSConnection.Password,
-		srv.Cfg.OTRSConnection.DBName,
-		srv.Cfg.OTRSConnection.SSLMode,
-		srv.userList(),
-	)
-	if err != nil {
+	// Unavailable OTRS is connected again by next synchronisation, configuration errors are fatal.
+	otrsDB, err := srv.newOTRSProvider(ctx)
+	if errors.Is(err, otrs.ErrUnavailable) {
 		log.Printf("Otrs initialisation error '%v'", err)
+	} else if err != nil {
+		log.Fatalf("Otrs initialisation error '%v'", err)
 	}
 	srv.OTRS = otrsDB
-	defer func() {
-		err := srv.OTRS.Stop()
+
+	// Runs periodic data collection to display the web page.
+	srv.Data = &httpServer.TodayStatistic{}
+	srv.Jobs, err = srv.newScheduler()
+	if err != nil {
+		log.Fatalf("Scheduler initialisation error '%v'", err)
+	}
+	srv.Jobs.Start(ctx)
+	go func() {
+		_ = srv.Jobs.Run(ctx, jobTodayRefresh) // Get initial data, error is logged by scheduler.
+	}()
+
+	// Initialise pages and start HTTP server.
+	srv.HTTP = goviewEcho.NewProvider(httpServer.Connectors{
+		TodayData:          srv.Data,
+		GetCurrentWeekData: srv.WeekConnector(0),
+		GetLastWeekData:    srv.WeekConnector(-1),
+		GetDayDetails:      srv.DetailsConnector(),
+		GetReport:          srv.ReportConnector(),
+		GetHistory:         srv.HistoryConnector(),
+		GetWDOList:         srv.WDOListConnector(),
+		SetWDO:             srv.setWDO,
+		RemoveWDO:          srv.removeWDO,
+		GetAbsences:        srv.AbsencesConnector(),
+		AddAbsence:         srv.addAbsence,
+		RemoveAbsence:      srv.removeAbsence,
+		GetJobs:            srv.JobsConnector(),
+		GetSyncState:       srv.SyncStateConnector(),
+		StartResync:        srv.StartResyncConnector(),
+		GetResyncProgress:  srv.ResyncProgressConnector(),
+		GetReconciliation:  srv.ReconciliationConnector(),
+		APIToken:           srv.Cfg.Web.APIToken,
+	})
+	go func() {
+		err := srv.HTTP.ListenAndServe(srv.Cfg.Web.Port)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("HTTP server error '%v'", err)
+			stop()
+		}
+	}()
+
+	// Read data from OTRS for last 20 days and store it into internal DB.
+	// Runs in background, so web interface is available meanwhile, shutdown waits for it.
+	srv.background.Add(1)
+	go func() {
+		defer srv.background.Done()
+		err := srv.GetOldStatisticFromOTRS(ctx)
 		if err != nil {
-			log.Printf("Otrs stop error '%v'", err)
+			log.Printf("get old data failed - '%v'", err)
 		}
 	}()
 
-	// Runs periodic data collection to display the web page.
-	srv.Data = &httpServer.TodayStatistic{}
-	go srv.RegularlyGetTodayData()
+	<-ctx.Done()
+	stop() // Second signal terminates service immediately.
+	srv.shutdown()
+}
+
+// Stop service: drain HTTP requests, wait for running jobs and re-sync, then close OTRS and internal DB.
+// OTRS queries of jobs are already canceled by root context, started writes into internal DB are finished.
+func (s *Service) shutdown() {
+	log.Printf("Shutdown started")
 
-	// Read data from OTRS for last 20 days and store if into internal DB.
-	err = srv.GetOldStatisticFromOTRS()
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err := s.HTTP.Shutdown(ctx)
 	if err != nil {
-		log.Printf("get old data failed - '%v'", err)
+		log.Printf("HTTP server shutdown error '%v'", err)
 	}
 
-	// Start WorkdayOverrideWorker.
-	// Wait for signal from web API and store received data into internal DB.
-	newWorkdayOverride := make(chan string, 100)
-	removeWorkdayOverride := make(chan string, 100)
-	go srv.WDOWorker(newWorkdayOverride, removeWorkdayOverride)
+	s.Jobs.Wait()
+	s.background.Wait()
 
-	// Initialise pages and start HTTP server.
-	srv.HTTP = goviewEcho.NewProvider(srv.Data, srv.WeekConnector(0), srv.WeekConnector(-1), newWorkdayOverride, removeWorkdayOverride)
-	srv.HTTP.ListenAndServe(srv.Cfg.Web.Port)
+	err = s.OTRS.Stop()
+	if err != nil {
+		log.Printf("Otrs stop error '%v'", err)
+	}
+	err = s.DB.Close()
+	if err != nil {
+		log.Printf("Internal DB close error '%v'", err)
+	}
 
+	log.Printf("Shutdown finished")
 }
 
-// Handle requests for override workdays.
-func (s *Service) WDOWorker(setWDO, removeWDO chan string) {
-	var date string
-	for {
-		select {
-		case date = <-setWDO:
-			s.setWDO(date)
-		case date = <-removeWDO:
-			s.removeWDO(date)
-		}
+// Initialise internal DB for configured driver.
+// Demo data is not stored into DB file.
+func (s *Service) newInternalDB() (internalDB.Provider, error) {
+	if s.Cfg.Mode == demoMode {
+		return gromSqlite3.NewDB(demoDBFileName, s.internalDBUserList())
+	}
+
+	c := s.Cfg.InternalDB
+	switch c.Driver {
+	case "", "sqlite":
+		return gromSqlite3.NewDB(c.FileName, s.internalDBUserList())
+	case "postgres":
+		return gormPostgres.NewDB(c.Host, c.Port, c.UserName, c.Password, c.DBName, c.SSLMode, s.internalDBUserList())
+	default:
+		return nil, fmt.Errorf("unknown internal DB driver '%s'", c.Driver)
 	}
 }
 
-// Add new overridden day into internal DB.
-func (s *Service) setWDO(date string) {
-	day, err := dateToUnixDay(date)
+// Initialise OTRS DB connector for configured driver.
+func (s *Service) newOTRSProvider(ctx context.Context) (otrs.Provider, error) {
+	if s.Cfg.Mode == demoMode {
+		return fake.NewProvider(s.Cfg.Demo.FixtureFile, s.Cfg.Demo.Seed, s.userList(), s.stateClassification())
+	}
+
+	c := s.Cfg.OTRSConnection
+	switch c.Driver {
+	case "", "postgres":
+		return postgre.NewProvider(ctx, c.Host, c.Port, c.UserName, c.Password, c.DBName, c.SSLMode, c.OvertimeField, s.userList(), s.stateClassification())
+	case "mysql":
+		return mysql.NewProvider(ctx, c.Host, c.Port, c.UserName, c.Password, c.DBName, c.SSLMode, c.OvertimeField, s.userList(), s.stateClassification())
+	case "rest":
+		return genericInterface.NewProvider(ctx, c.URL, c.WebService, c.UserName, c.Password, c.OvertimeField, s.userIDMap(), s.stateClassification())
+	default:
+		return nil, fmt.Errorf("unknown OTRS DB driver '%s'", c.Driver)
+	}
+}
+
+// Add new overridden day into internal DB or replace existing one.
+// If type is not specified, day type is flipped: weekend becomes transferred workday and weekday becomes holiday.
+func (s *Service) setWDO(wo httpServer.DayOverride) error {
+	day, err := parseRequestDate(wo.Date)
 	if err != nil {
-		log.Printf("can't set workday owerride '%v'", err)
-		return
+		return err
+	}
+	if wo.Type == "" {
+		wo.Type = internalDB.DayTypeHoliday
+		weekday := time.Unix(day*60*60*24, 0).UTC().Weekday()
+		if weekday == time.Saturday || weekday == time.Sunday {
+			wo.Type = internalDB.DayTypeTransferredWorkday
+		}
+	}
+	if !internalDB.IsValidDayType(wo.Type) {
+		return fmt.Errorf("%w: unknown day type '%s'", httpServer.ErrBadRequest, wo.Type)
+	}
+	var norm int64
+	if wo.Norm != "" {
+		norm, err = strconv.ParseInt(wo.Norm, 10, 64)
+		if err != nil || norm < 0 {
+			return fmt.Errorf("%w: invalid norm '%s', expected minutes", httpServer.ErrBadRequest, wo.Norm)
+		}
 	}
 
-	s.DB.SetWorkdayOverride(day)
+	err = s.DB.SetWorkdayOverride(internalDB.WorkdayOverride{
+		Day:    day,
+		Type:   wo.Type,
+		Norm:   norm,
+		Reason: wo.Reason,
+		Author: wo.Author,
+	})
+	if err != nil {
+		return fmt.Errorf("can't set workday override '%w'", err)
+	}
+	return nil
 }
 
 // Remove overridden day internal DB.
-func (s *Service) removeWDO(date string) {
-	day, err := dateToUnixDay(date)
+func (s *Service) removeWDO(date string) error {
+	day, err := parseRequestDate(date)
 	if err != nil {
-		log.Printf("can't remove workday owerride '%v'", err)
-		return
+		return err
 	}
 
-	s.DB.RemoveWorkdayOverride(day)
+	err = s.DB.RemoveWorkdayOverride(day)
+	if err != nil {
+		return fmt.Errorf("can't remove workday override '%w'", err)
+	}
+	return nil
+}
+
+// Calculate day number for date received from web request.
+// Returned error wraps httpServer.ErrBadRequest.
+func parseRequestDate(date string) (int64, error) {
+	day, err := dateToUnixDay(date)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid date '%s', expected format is '2006.01.02'", httpServer.ErrBadRequest, date)
+	}
+	return day, nil
 }
 
 // Calculate day number since 1970.01.01 . 1970.01.01 day number is 0.
@@ -138,63 +302,39 @@ func dateToUnixDay(dateString string) (int64, error) {
 	return dayUnix, nil
 }
 
-// Regularly (every 15 minutes) get today data from OTRS DB.
-// Correct the time to synchronize with the quarters of an hour.
-func (s *Service) RegularlyGetTodayData() {
-	var min time.Duration
-	var err error
-
-	// Get initial data.
-	err = s.UpdateTodayStatistic()
-	if err != nil {
-		log.Printf("Error wile update data '%v", err)
-		return
-	}
-
-	// Correct time.
-	min = time.Duration(60 - time.Now().Minute())
-	time.Sleep((time.Minute % 15) * min)
-
-	for {
-		err = s.UpdateTodayStatistic()
-		if err != nil {
-			log.Printf("Error wile update data '%v", err)
-			return
-		}
-		time.Sleep(time.Minute * 15)
-	}
-}
-
-// Regularly (every day) get yesterday data from OTRS DB.
-// Correct the time to synchronize with midnight.
-func (s *Service) RegularlyGetYesterdayData() {
-	var min, hour time.Duration
-	var err error
-	for {
-		err = s.GetDayFromOTRSAndStore(0)
-		if err != nil {
-			log.Printf("Error wile get yestarday data from OTRS '%v", err)
-			return
-		}
-		min = time.Duration(60 - time.Now().Minute())
-		hour = time.Duration(23 - time.Now().Hour())
-		time.Sleep(time.Minute*min + 5)
-		time.Sleep(time.Hour * hour)
-	}
+// Calculate date in "2006.01.02" format from day number since 1970.01.01 .
+func unixDayToDate(day int64) string {
+	return time.Unix(day*60*60*24, 0).UTC().Format("2006.01.02")
 }
 
 // Collect data from OTRS and store in into variable.
 // Used in today web page.
-func (s *Service) UpdateTodayStatistic() error {
+func (s *Service) UpdateTodayStatistic(ctx context.Context) error {
 	// Update data into internal DB.
 	// For actual statistic on current week page.
-	err := s.GetDayFromOTRSAndStore(0)
+	err := s.GetDayFromOTRSAndStore(ctx, 0, syncSourceRegular)
 	if err != nil {
 		return err
 	}
 
 	// Get extended today data.
-	OTRSData, err := s.OTRS.GetTodayData()
+	OTRSData, err := s.OTRS.GetTodayData(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Get today norm with respect to overridden days.
+	today, err := dateToUnixDay(time.Now().Format("2006.01.02"))
+	if err != nil {
+		return err
+	}
+	overrideList, err := s.DB.GetOverrideByDaySequence(today, 1)
+	if err != nil {
+		return err
+	}
+	weekday := time.Now().Weekday()
+	norm := newWorkday(today, weekday != time.Saturday && weekday != time.Sunday, overrideList).Norm
+	absence, err := s.absenceByLoginAndDay(today, today)
 	if err != nil {
 		return err
 	}
@@ -205,12 +345,20 @@ func (s *Service) UpdateTodayStatistic() error {
 	// Create slice for link between OTRS data and
 	SliceLinkMap := make(map[string]int, 0)
 	for id, row := range OTRSData {
-		SliceLinkMap[row.LastName] = id
+		SliceLinkMap[row.Login] = id
 	}
 
 	// Fill the table with collected data in certain order.
-	for i, user := range getUserOrder() {
-		currentRow := AssembleTodayRow(OTRSData, SliceLinkMap[user.LastName], user.LastName, user.WorkShiftColor)
+	for i, user := range s.getUserOrder() {
+		// Show zero data for users missing in OTRS data.
+		if _, ok := SliceLinkMap[user.Login]; !ok {
+			SliceLinkMap[user.Login] = len(OTRSData)
+			OTRSData = append(OTRSData, otrs.DayStatisticRow{Login: user.Login})
+		}
+		currentRow := AssembleTodayRow(OTRSData, SliceLinkMap[user.Login], user.LastName, user.WorkShiftColor, norm)
+		if _, ok := absence[user.Login][today]; ok {
+			currentRow.TimeAccountedColor = absenceColor
+		}
 		webDataList = append(webDataList, currentRow)
 		if user.LastInGroup {
 			webDataList[i].LastInGroup = true
@@ -227,17 +375,10 @@ func AssembleTodayRow(
 	otrsIndex int,
 	lastName string,
 	workShiftColor string,
+	norm int64,
 ) httpServer.TodayStatisticRow {
 	// Calculate time cell color.
-	var timeAccountedColor string
-	switch {
-	case OTRSData[otrsIndex].WorkTime+OTRSData[otrsIndex].OverTime < 240:
-		timeAccountedColor = "bad-grid-col"
-	case OTRSData[otrsIndex].WorkTime+OTRSData[otrsIndex].OverTime < 300:
-		timeAccountedColor = "average-grid-col"
-	default:
-		timeAccountedColor = "good-grid-col"
-	}
+	timeAccountedColor := timeColor(int64(OTRSData[otrsIndex].WorkTime+OTRSData[otrsIndex].OverTime), norm)
 
 	// Assemble row data.
 	return httpServer.TodayStatisticRow{
@@ -252,68 +393,158 @@ func AssembleTodayRow(
 	}
 }
 
-// TODO - get data from config file
-func getUserOrder() []httpServer.UserCell {
+// Get display order of configured users.
+// Users are shown in config order, group ends when next user has another command.
+func (s *Service) getUserOrder() []httpServer.UserCell {
 	order := make([]httpServer.UserCell, 0, 32)
-
-	order = append(order, httpServer.UserCell{LastName: "Вахрамеев", WorkShiftColor: morningShiftColor, LastInGroup: false})
-	order = append(order, httpServer.UserCell{LastName: "Мостынец", WorkShiftColor: morningShiftColor, LastInGroup: false})
-	order = append(order, httpServer.UserCell{LastName: "Рыжко", WorkShiftColor: morningShiftColor, LastInGroup: false})
-	order = append(order, httpServer.UserCell{LastName: "Ельцов", WorkShiftColor: eveningShiftColor, LastInGroup: false})
-	order = append(order, httpServer.UserCell{LastName: "Кирьяков", WorkShiftColor: eveningShiftColor, LastInGroup: false})
-	order = append(order, httpServer.UserCell{LastName: "Кротов", WorkShiftColor: eveningShiftColor, LastInGroup: false})
-	order = append(order, httpServer.UserCell{LastName: "Лапенков", WorkShiftColor: eveningShiftColor, LastInGroup: true})
-
-	order = append(order, httpServer.UserCell{LastName: "Аманов", WorkShiftColor: morningShiftColor, LastInGroup: false})
-	order = append(order, httpServer.UserCell{LastName: "Асланян", WorkShiftColor: morningShiftColor, LastInGroup: false})
-	order = append(order, httpServer.UserCell{LastName: "Лантух", WorkShiftColor: morningShiftColor, LastInGroup: false})
-	order = append(order, httpServer.UserCell{LastName: "Мехряков", WorkShiftColor: morningShiftColor, LastInGroup: false})
-	order = append(order, httpServer.UserCell{LastName: "Техов", WorkShiftColor: morningShiftColor, LastInGroup: false})
-	order = append(order, httpServer.UserCell{LastName: "Ермаков", WorkShiftColor: eveningShiftColor, LastInGroup: false})
-	order = append(order, httpServer.UserCell{LastName: "Шенцов", WorkShiftColor: eveningShiftColor, LastInGroup: true})
-
+	for i, user := range s.Cfg.UserList {
+		workShiftColor := eveningShiftColor
+		if user.WorkShift == morningShift {
+			workShiftColor = morningShiftColor
+		}
+		lastInGroup := i == len(s.Cfg.UserList)-1 || s.Cfg.UserList[i+1].Command != user.Command
+		order = append(order, httpServer.UserCell{Login: user.Login, LastName: user.LastName, WorkShiftColor: workShiftColor, LastInGroup: lastInGroup})
+	}
 	return order
 }
 
-// Read data from OTRS for last 20 days and store if into internal DB.
-func (s *Service) GetOldStatisticFromOTRS() error {
-	var err error
-	var i int64
-	for i = 0; i > -20; i-- {
-		err = s.GetDayFromOTRSAndStore(i)
-		if err != nil {
-			return err
+// Get display name of configured user. Return login for unknown users.
+func (s *Service) displayName(login string) string {
+	for _, user := range s.Cfg.UserList {
+		if user.Login == login {
+			return user.LastName
 		}
 	}
-	return nil
+	return login
+}
+
+// Read data from OTRS for last 20 days and store if into internal DB.
+func (s *Service) GetOldStatisticFromOTRS(ctx context.Context) error {
+	today := truncateToDay(time.Now())
+	return s.SyncRange(ctx, today.AddDate(0, 0, -19), today.AddDate(0, 0, 1), syncSourceStartup)
 }
 
 // Get day statistic from OTRS and store accounted time into internal DB.
-func (s *Service) GetDayFromOTRSAndStore(dayOffset int64) error {
-	tmpTime := time.Now().Add((time.Hour * 24) * time.Duration(dayOffset)) // Add day offset to current time.
-	OTRSData, err := s.OTRS.GetCustomDayData(tmpTime)                      // Collect target day data for from OTRS.
+// Source is stored into change history of accounted time.
+func (s *Service) GetDayFromOTRSAndStore(ctx context.Context, dayOffset int64, source string) error {
+	day := truncateToDay(time.Now()).AddDate(0, 0, int(dayOffset)) // Add day offset to current day.
+	return s.SyncRange(ctx, day, day.AddDate(0, 0, 1), source)
+}
+
+// Get statistic for [from, to) period from OTRS and store it into internal DB.
+// Every OTRS query covers whole period, so long periods are synchronised fast.
+// Users without accounted time on some day get zero time for this day.
+// Source is stored into change history of accounted time.
+func (s *Service) SyncRange(ctx context.Context, from, to time.Time, source string) error {
+	from = truncateToDay(from)
+	to = truncateToDay(to)
+
+	// Collect period data from OTRS.
+	rangeData, err := s.OTRS.GetRangeData(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	categoryData, err := s.OTRS.GetCategoryData(ctx, from, to)
 	if err != nil {
 		return err
 	}
 
-	var unixDay int64
-	unixDay, err = dateToUnixDay(tmpTime.Format("2006.01.02")) // Calculate day number.
+	// Group collected data by day number.
+	atByDay := make(map[int64]map[string]otrs.RangeStatisticRow)
+	for _, row := range rangeData {
+		unixDay, err := dateToUnixDay(row.Day.Format("2006.01.02"))
+		if err != nil {
+			return err
+		}
+		if atByDay[unixDay] == nil {
+			atByDay[unixDay] = make(map[string]otrs.RangeStatisticRow)
+		}
+		atByDay[unixDay][row.Login] = row
+	}
+	ctByDay := make(map[int64][]internalDB.CategoryTime)
+	for _, row := range categoryData {
+		unixDay, err := dateToUnixDay(row.Day.Format("2006.01.02"))
+		if err != nil {
+			return err
+		}
+		ctByDay[unixDay] = append(ctByDay[unixDay], internalDB.CategoryTime{
+			Login:    row.Login,
+			Category: row.Category,
+			Name:     row.Name,
+			WorkTime: int64(row.WorkTime),
+			OverTime: int64(row.OverTime),
+		})
+	}
 
-	// Store collected data for every person.
-	for _, row := range OTRSData {
+	// Store collected data for every day and every person in one transaction.
+	atList := make([]internalDB.AccountedTime, 0, 32)
+	ctToStore := make(map[int64][]internalDB.CategoryTime)
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		unixDay, err := dateToUnixDay(day.Format("2006.01.02"))
 		if err != nil {
 			return err
 		}
-		s.DB.AddOrUpdateAccountedTime(row.LastName, unixDay, int64(row.WorkTime), int64(row.OverTime))
+		for _, login := range s.userList() {
+			row := atByDay[unixDay][login]
+			atList = append(atList, internalDB.AccountedTime{
+				Day:      unixDay,
+				Login:    login,
+				WorkTime: int64(row.WorkTime),
+				OverTime: int64(row.OverTime),
+			})
+		}
+		ctToStore[unixDay] = ctByDay[unixDay]
+	}
+	err = s.DB.StoreSyncData(atList, ctToStore, source)
+	if err != nil {
+		return fmt.Errorf("store data for '%s' - '%s' failed '%w'", from.Format("2006.01.02"), to.Format("2006.01.02"), err)
 	}
+
 	return nil
 }
 
-// Get slice of LastName from configured users.
+// Return start of the day in the same location.
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// Get slice of Login from configured users.
 func (s *Service) userList() []string {
 	ul := make([]string, 0, 32)
 	for _, user := range s.Cfg.UserList {
-		ul = append(ul, user.LastName)
+		ul = append(ul, user.Login)
+	}
+	return ul
+}
+
+// Get map from OTRS user ID to Login for configured users with specified ID.
+func (s *Service) userIDMap() map[int]string {
+	um := make(map[int]string, len(s.Cfg.UserList))
+	for _, user := range s.Cfg.UserList {
+		if user.UserID != 0 {
+			um[user.UserID] = user.Login
+		}
+	}
+	return um
+}
+
+// Get ticket state classification from config. Use default for state types which are not configured.
+func (s *Service) stateClassification() otrs.StateClassification {
+	sc := otrs.DefaultStateClassification()
+	if len(s.Cfg.TicketStates.ClosedTypes) > 0 {
+		sc.Closed = s.Cfg.TicketStates.ClosedTypes
+	}
+	if len(s.Cfg.TicketStates.OpenTypes) > 0 {
+		sc.Open = s.Cfg.TicketStates.OpenTypes
+	}
+	return sc
+}
+
+// Get logins and last names of configured users.
+func (s *Service) internalDBUserList() []internalDB.User {
+	ul := make([]internalDB.User, 0, len(s.Cfg.UserList))
+	for _, user := range s.Cfg.UserList {
+		ul = append(ul, internalDB.User{Login: user.Login, LastName: user.LastName})
 	}
 	return ul
 }